@@ -0,0 +1,9 @@
+data "zerotier_member" "car" {
+  network_id = zerotier_network.bobs_garage.id
+  member_id  = "b0b5ca7000"
+}
+
+data "zerotier_member" "bike" {
+  network_id = zerotier_network.bobs_garage.id
+  name       = "bobs_bike"
+}
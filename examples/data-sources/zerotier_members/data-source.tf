@@ -1,3 +1,11 @@
 data "zerotier_members" "members" {
   network_id = zerotier_network.bobs_garage.id
 }
+
+# the authorized members named like web servers that were online today.
+data "zerotier_members" "web" {
+  network_id    = zerotier_network.bobs_garage.id
+  authorized    = true
+  name_regex    = "^web-"
+  online_within = "24h"
+}
@@ -1,3 +1,8 @@
 data "zerotier_network" "bob" {
   id = zerotier_network.bobs_garage.id
 }
+
+# a network shared by another team, found by its name.
+data "zerotier_network" "shared" {
+  name = "shared_services"
+}
@@ -0,0 +1,4 @@
+data "zerotier_networks" "bobs" {
+  name_regex = "^bobs_"
+  private    = true
+}
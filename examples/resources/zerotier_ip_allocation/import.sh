@@ -0,0 +1,2 @@
+# the ID is the network ID, member ID and address, separated by slashes
+terraform import zerotier_ip_allocation.server "8056c2e21c1930be/1122334455/10.0.0.10"
@@ -0,0 +1,15 @@
+resource "zerotier_member" "server" {
+  name       = "server"
+  member_id  = zerotier_identity.server.id
+  network_id = zerotier_network.alicenet.id
+}
+
+resource "zerotier_ip_allocation" "server" {
+  network_id = zerotier_network.alicenet.id
+  member_id  = zerotier_member.server.member_id
+
+  # a new address is allocated whenever the server is rebuilt.
+  keepers = {
+    identity = zerotier_identity.server.id
+  }
+}
@@ -1 +1,3 @@
+# the ID is the network ID and member ID, separated by a slash or a dash
+terraform import zerotier_member.alice "8056c2e21c1930be/1122334455"
 terraform import zerotier_member.alice "8056c2e21c1930be-1122334455"
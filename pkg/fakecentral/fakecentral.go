@@ -0,0 +1,697 @@
+// Package fakecentral is an in-memory stand-in for the ZeroTier Central API.
+// It implements just enough of the endpoints used by the provider for it to
+// be exercised without a real account, token or network connection. Point
+// the provider's zerotier_central_url at URL() and use Token as the
+// zerotier_central_token.
+package fakecentral
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+const (
+	// ControllerID is the 10 digit controller prefix given to every network
+	// created by the fake.
+	ControllerID = "f4ce0c7a1e"
+	// UserID is the ID of the single user the fake knows about.
+	UserID = "00000000-0000-0000-0000-00000000f4ce"
+)
+
+type object = map[string]interface{}
+
+// Server is a fake ZeroTier Central. The zero value is not usable; use New.
+type Server struct {
+	// Token is the API token the fake will accept.
+	Token string
+
+	srv *httptest.Server
+
+	mutex     sync.Mutex
+	networks  map[string]object
+	members   map[string]map[string]object
+	tokens    map[string]string
+	serial    uint32
+	requests  int
+	inFlight  int
+	maxFlight int
+	latency   time.Duration
+
+	failures   int
+	failCode   int
+	retryAfter string
+}
+
+// New starts a fake Central server accepting token. Close it when finished.
+func New(token string) *Server {
+	s := &Server{
+		Token:    token,
+		networks: map[string]object{},
+		members:  map[string]map[string]object{},
+		tokens:   map[string]string{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", s.getStatus)
+	mux.HandleFunc("GET /api/randomToken", s.getRandomToken)
+	mux.HandleFunc("POST /api/user/{userID}/token", s.addToken)
+	mux.HandleFunc("DELETE /api/user/{userID}/token/{name}", s.deleteToken)
+	mux.HandleFunc("GET /api/network", s.listNetworks)
+	mux.HandleFunc("POST /api/network", s.newNetwork)
+	mux.HandleFunc("GET /api/network/{networkID}", s.getNetwork)
+	mux.HandleFunc("POST /api/network/{networkID}", s.updateNetwork)
+	mux.HandleFunc("DELETE /api/network/{networkID}", s.deleteNetwork)
+	mux.HandleFunc("GET /api/network/{networkID}/member", s.listMembers)
+	mux.HandleFunc("GET /api/network/{networkID}/member/{memberID}", s.getMember)
+	mux.HandleFunc("POST /api/network/{networkID}/member/{memberID}", s.updateMember)
+	mux.HandleFunc("DELETE /api/network/{networkID}/member/{memberID}", s.deleteMember)
+
+	s.srv = httptest.NewServer(s.count(s.authenticate(mux)))
+
+	return s
+}
+
+// URL is the API endpoint of the fake, suitable for zerotier_central_url.
+func (s *Server) URL() string {
+	return s.srv.URL + "/api"
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Requests returns the number of requests the fake has received.
+func (s *Server) Requests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.requests
+}
+
+// MaxInFlight returns the most requests the fake has been serving at once.
+func (s *Server) MaxInFlight() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.maxFlight
+}
+
+// SetLatency delays every response by d, so that concurrent requests overlap.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.latency = d
+}
+
+// FailNext makes the next count requests fail with the HTTP status code. If
+// retryAfter is not empty, it is sent as the Retry-After header.
+func (s *Server) FailNext(count, code int, retryAfter string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures = count
+	s.failCode = code
+	s.retryAfter = retryAfter
+}
+
+// Network returns a copy of the stored network, or nil if it does not exist.
+func (s *Server) Network(id string) *spec.Network {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	n, ok := s.networks[id]
+	if !ok {
+		return nil
+	}
+
+	res := &spec.Network{}
+	convert(n, res)
+	return res
+}
+
+// NetworkIDs returns the IDs of every stored network, sorted.
+func (s *Server) NetworkIDs() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ids := []string{}
+	for id := range s.networks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Member returns a copy of the stored member, or nil if it does not exist.
+func (s *Server) Member(networkID, memberID string) *spec.Member {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, ok := s.members[networkID][memberID]
+	if !ok {
+		return nil
+	}
+
+	res := &spec.Member{}
+	convert(m, res)
+	return res
+}
+
+// SetNetwork merges n into the stored network as if the change came from
+// outside of terraform, such as the Central UI. The network must exist.
+func (s *Server) SetNetwork(networkID string, n *spec.Network) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	merge(s.networks[networkID], toObject(n))
+}
+
+// SetMember merges m into the stored member as if the change came from
+// outside of terraform, such as the Central UI or the node itself. Unlike the
+// API, read-only fields like lastOnline may be set this way. The member is
+// created if it does not exist.
+func (s *Server) SetMember(networkID, memberID string, m *spec.Member) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	obj := s.findOrCreateMember(networkID, memberID)
+	merge(obj, toObject(m))
+}
+
+// DeleteNetwork removes a network and its members as if it was deleted
+// outside of terraform.
+func (s *Server) DeleteNetwork(networkID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.networks, networkID)
+	delete(s.members, networkID)
+}
+
+// DeleteMember removes a member as if it was deleted outside of terraform.
+func (s *Server) DeleteMember(networkID, memberID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.members[networkID], memberID)
+}
+
+// APITokens returns the names of the API tokens created through the fake.
+func (s *Server) APITokens() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	names := []string{}
+	for name := range s.tokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+//
+// handlers
+//
+
+func (s *Server) count(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		s.requests++
+		s.inFlight++
+		s.maxFlight = max(s.maxFlight, s.inFlight)
+		latency := s.latency
+		s.mutex.Unlock()
+
+		defer func() {
+			s.mutex.Lock()
+			s.inFlight--
+			s.mutex.Unlock()
+		}()
+
+		time.Sleep(latency)
+
+		s.mutex.Lock()
+		if s.failures > 0 {
+			s.failures--
+			code, retryAfter := s.failCode, s.retryAfter
+			s.mutex.Unlock()
+
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+
+			writeError(w, code)
+			return
+		}
+
+		s.mutex.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "bearer "+s.Token {
+			writeError(w, http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tokens := []string{}
+	for name := range s.tokens {
+		tokens = append(tokens, name)
+	}
+	sort.Strings(tokens)
+
+	writeJSON(w, object{
+		"id":         "fakecentral",
+		"apiVersion": "4",
+		"clock":      now(),
+		"user": object{
+			"id":          UserID,
+			"displayName": "Fake User",
+			"tokens":      tokens,
+		},
+	})
+}
+
+func (s *Server) getRandomToken(w http.ResponseWriter, r *http.Request) {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+
+	writeJSON(w, object{
+		"clock": now(),
+		"hex":   hex.EncodeToString(buf),
+		"token": hex.EncodeToString(buf),
+	})
+}
+
+func (s *Server) addToken(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("userID") != UserID {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	var token spec.APIToken
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil || token.TokenName == nil || token.Token == nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.tokens[*token.TokenName]; ok {
+		writeError(w, http.StatusConflict)
+		return
+	}
+
+	s.tokens[*token.TokenName] = *token.Token
+	writeJSON(w, object{})
+}
+
+func (s *Server) deleteToken(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	name := r.PathValue("name")
+
+	if _, ok := s.tokens[name]; !ok || r.PathValue("userID") != UserID {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	delete(s.tokens, name)
+	writeJSON(w, object{})
+}
+
+func (s *Server) listNetworks(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ids := []string{}
+	for id := range s.networks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	res := []object{}
+	for _, id := range ids {
+		res = append(res, s.networks[id])
+	}
+
+	writeJSON(w, res)
+}
+
+func (s *Server) newNetwork(w http.ResponseWriter, r *http.Request) {
+	body := object{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.serial++
+	id := fmt.Sprintf("%s%06x", ControllerID, s.serial)
+
+	n := newNetworkObject(id)
+	merge(n, body)
+	s.fixNetworkIdentity(n, id)
+
+	s.networks[id] = n
+	s.members[id] = map[string]object{}
+
+	writeJSON(w, n)
+}
+
+func (s *Server) getNetwork(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	n, ok := s.networks[r.PathValue("networkID")]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, n)
+}
+
+func (s *Server) updateNetwork(w http.ResponseWriter, r *http.Request) {
+	body := object{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := r.PathValue("networkID")
+
+	n, ok := s.networks[id]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	merge(n, body)
+	s.fixNetworkIdentity(n, id)
+	n["config"].(object)["lastModified"] = now()
+
+	writeJSON(w, n)
+}
+
+func (s *Server) deleteNetwork(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := r.PathValue("networkID")
+
+	if _, ok := s.networks[id]; !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	delete(s.networks, id)
+	delete(s.members, id)
+
+	writeJSON(w, object{})
+}
+
+func (s *Server) listMembers(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	members, ok := s.members[r.PathValue("networkID")]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	ids := []string{}
+	for id := range members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	res := []object{}
+	for _, id := range ids {
+		res = append(res, members[id])
+	}
+
+	writeJSON(w, res)
+}
+
+func (s *Server) getMember(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, ok := s.members[r.PathValue("networkID")][r.PathValue("memberID")]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, m)
+}
+
+func (s *Server) updateMember(w http.ResponseWriter, r *http.Request) {
+	body := object{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	networkID, memberID := r.PathValue("networkID"), r.PathValue("memberID")
+
+	if _, ok := s.networks[networkID]; !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	// like central, posting to a member that does not exist creates it.
+	m := s.findOrCreateMember(networkID, memberID)
+
+	for _, key := range []string{"clientVersion", "lastOnline", "lastSeen", "physicalAddress", "protocolVersion"} {
+		delete(body, key)
+	}
+
+	merge(m, body)
+	fixMemberIdentity(m, networkID, memberID)
+
+	config := m["config"].(object)
+	config["revision"] = config["revision"].(float64) + 1
+
+	writeJSON(w, m)
+}
+
+func (s *Server) deleteMember(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	networkID, memberID := r.PathValue("networkID"), r.PathValue("memberID")
+
+	if _, ok := s.members[networkID][memberID]; !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	delete(s.members[networkID], memberID)
+
+	writeJSON(w, object{})
+}
+
+//
+// object helpers
+//
+
+func (s *Server) findOrCreateMember(networkID, memberID string) object {
+	if _, ok := s.members[networkID]; !ok {
+		s.members[networkID] = map[string]object{}
+	}
+
+	m, ok := s.members[networkID][memberID]
+	if !ok {
+		m = newMemberObject(networkID, memberID)
+		s.members[networkID][memberID] = m
+	}
+
+	return m
+}
+
+func (s *Server) fixNetworkIdentity(n object, id string) {
+	n["id"] = id
+	n["config"].(object)["id"] = id
+}
+
+func fixMemberIdentity(m object, networkID, memberID string) {
+	m["id"] = networkID + "-" + memberID
+	m["networkId"] = networkID
+	m["nodeId"] = memberID
+	m["config"].(object)["id"] = memberID
+}
+
+func newNetworkObject(id string) object {
+	ts := now()
+
+	return object{
+		"id":                    id,
+		"clock":                 ts,
+		"description":           "",
+		"rulesSource":           "accept;",
+		"ownerId":               UserID,
+		"onlineMemberCount":     float64(0),
+		"authorizedMemberCount": float64(0),
+		"totalMemberCount":      float64(0),
+		"capabilitiesByName":    object{},
+		"tagsByName":            object{},
+		"config": object{
+			"id":                id,
+			"name":              "",
+			"creationTime":      ts,
+			"lastModified":      ts,
+			"private":           true,
+			"enableBroadcast":   true,
+			"multicastLimit":    float64(32),
+			"mtu":               float64(2800),
+			"ipAssignmentPools": []interface{}{},
+			"routes":            []interface{}{},
+			"rules":             []interface{}{},
+			"tags":              []interface{}{},
+			"capabilities":      []interface{}{},
+			"v4AssignMode":      object{"zt": false},
+			"v6AssignMode":      object{"zt": false, "6plane": false, "rfc4193": false},
+			"dns":               object{"domain": "", "servers": nil},
+			"ssoConfig":         object{"enabled": false, "mode": "default", "allowList": nil},
+		},
+	}
+}
+
+func newMemberObject(networkID, memberID string) object {
+	ts := now()
+
+	m := object{
+		"networkId":           networkID,
+		"nodeId":              memberID,
+		"controllerId":        networkID[:10],
+		"clock":               ts,
+		"name":                "",
+		"description":         "",
+		"hidden":              false,
+		"lastOnline":          float64(0),
+		"lastSeen":            float64(0),
+		"physicalAddress":     "",
+		"clientVersion":       "",
+		"protocolVersion":     float64(0),
+		"supportsRulesEngine": false,
+		"config": object{
+			"activeBridge":         false,
+			"authorized":           false,
+			"capabilities":         []interface{}{},
+			"creationTime":         ts,
+			"identity":             "",
+			"ipAssignments":        []interface{}{},
+			"lastAuthorizedTime":   float64(0),
+			"lastDeauthorizedTime": float64(0),
+			"noAutoAssignIps":      false,
+			"revision":             float64(0),
+			"ssoExempt":            false,
+			"tags":                 []interface{}{},
+			"vMajor":               float64(-1),
+			"vMinor":               float64(-1),
+			"vRev":                 float64(-1),
+			"vProto":               float64(-1),
+		},
+	}
+
+	fixMemberIdentity(m, networkID, memberID)
+
+	return m
+}
+
+// merge copies every non-null value in src into dst, descending into nested
+// objects. This mirrors how central treats partial updates.
+func merge(dst, src object) {
+	for key, value := range src {
+		if value == nil {
+			continue
+		}
+
+		if srcObj, ok := value.(object); ok {
+			if dstObj, ok := dst[key].(object); ok {
+				merge(dstObj, srcObj)
+				continue
+			}
+		}
+
+		dst[key] = value
+	}
+}
+
+func toObject(i interface{}) object {
+	content, err := json.Marshal(i)
+	if err != nil {
+		panic(err)
+	}
+
+	obj := object{}
+	if err := json.Unmarshal(content, &obj); err != nil {
+		panic(err)
+	}
+
+	return obj
+}
+
+func convert(obj object, i interface{}) {
+	content, err := json.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := json.Unmarshal(content, i); err != nil {
+		panic(err)
+	}
+}
+
+func now() float64 {
+	return float64(time.Now().UnixMilli())
+}
+
+func writeJSON(w http.ResponseWriter, i interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(i)
+}
+
+func writeError(w http.ResponseWriter, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(object{"message": strings.ToLower(http.StatusText(code))})
+}
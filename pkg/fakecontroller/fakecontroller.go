@@ -0,0 +1,389 @@
+// Package fakecontroller is an in-memory stand-in for the network controller
+// embedded in zerotier-one, as reached through the node's local /controller
+// JSON API. Like the real controller it only keeps the fields it knows about,
+// so the provider's self-hosted backend can be tested without a running node.
+package fakecontroller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Address is the node address of the fake controller, and so the prefix of
+// every network it creates.
+const Address = "c0ffee0123"
+
+type object = map[string]interface{}
+
+var (
+	networkFields = []string{
+		"name", "private", "enableBroadcast", "multicastLimit", "mtu",
+		"v4AssignMode", "v6AssignMode", "routes", "ipAssignmentPools",
+		"rules", "rulesSource", "tags", "capabilities", "dns",
+		"remoteTraceTarget", "remoteTraceLevel",
+	}
+	memberFields = []string{
+		"name", "authorized", "activeBridge", "noAutoAssignIps",
+		"ipAssignments", "tags", "capabilities", "ssoExempt",
+		"remoteTraceTarget", "remoteTraceLevel",
+	}
+)
+
+// Server is a fake zerotier-one controller. The zero value is not usable;
+// use New.
+type Server struct {
+	// Token is the service API token (authtoken.secret) the fake accepts.
+	Token string
+
+	srv *httptest.Server
+
+	mutex    sync.Mutex
+	networks map[string]object
+	members  map[string]map[string]object
+	serial   uint32
+}
+
+// New starts a fake controller accepting token. Close it when finished.
+func New(token string) *Server {
+	s := &Server{
+		Token:    token,
+		networks: map[string]object{},
+		members:  map[string]map[string]object{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.getStatus)
+	mux.HandleFunc("GET /controller/network", s.listNetworks)
+	mux.HandleFunc("GET /controller/network/{networkID}", s.getNetwork)
+	mux.HandleFunc("POST /controller/network/{networkID}", s.postNetwork)
+	mux.HandleFunc("DELETE /controller/network/{networkID}", s.deleteNetwork)
+	mux.HandleFunc("GET /controller/network/{networkID}/member", s.listMembers)
+	mux.HandleFunc("GET /controller/network/{networkID}/member/{memberID}", s.getMember)
+	mux.HandleFunc("POST /controller/network/{networkID}/member/{memberID}", s.postMember)
+	mux.HandleFunc("DELETE /controller/network/{networkID}/member/{memberID}", s.deleteMember)
+
+	s.srv = httptest.NewServer(s.authenticate(mux))
+
+	return s
+}
+
+// URL is the service API endpoint of the fake.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Network returns the raw stored network object, or nil if it does not exist.
+func (s *Server) Network(id string) map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return copyObject(s.networks[id])
+}
+
+// Member returns the raw stored member object, or nil if it does not exist.
+func (s *Server) Member(networkID, memberID string) map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return copyObject(s.members[networkID][memberID])
+}
+
+//
+// handlers
+//
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ZT1-Auth") != s.Token {
+			writeError(w, http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, object{
+		"address": Address,
+		"online":  true,
+		"version": "1.14.0",
+		"clock":   now(),
+	})
+}
+
+func (s *Server) listNetworks(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ids := []string{}
+	for id := range s.networks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	writeJSON(w, ids)
+}
+
+func (s *Server) getNetwork(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	n, ok := s.networks[r.PathValue("networkID")]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, n)
+}
+
+func (s *Server) postNetwork(w http.ResponseWriter, r *http.Request) {
+	body := object{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := r.PathValue("networkID")
+
+	if !strings.HasPrefix(id, Address) || len(id) != 16 {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	// like the real controller, underscores ask for a free network ID.
+	if strings.HasSuffix(id, "______") {
+		s.serial++
+		id = fmt.Sprintf("%s%06x", Address, s.serial)
+	}
+
+	n, ok := s.networks[id]
+	if !ok {
+		n = newNetworkObject(id)
+		s.networks[id] = n
+		s.members[id] = map[string]object{}
+	}
+
+	for _, field := range networkFields {
+		if value, ok := body[field]; ok && value != nil {
+			n[field] = value
+		}
+	}
+
+	n["revision"] = n["revision"].(float64) + 1
+
+	writeJSON(w, n)
+}
+
+func (s *Server) deleteNetwork(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := r.PathValue("networkID")
+
+	n, ok := s.networks[id]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	delete(s.networks, id)
+	delete(s.members, id)
+
+	writeJSON(w, n)
+}
+
+func (s *Server) listMembers(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	members, ok := s.members[r.PathValue("networkID")]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	res := object{}
+	for id, m := range members {
+		res[id] = m["revision"]
+	}
+
+	writeJSON(w, res)
+}
+
+func (s *Server) getMember(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m, ok := s.members[r.PathValue("networkID")][r.PathValue("memberID")]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, m)
+}
+
+func (s *Server) postMember(w http.ResponseWriter, r *http.Request) {
+	body := object{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	networkID, memberID := r.PathValue("networkID"), r.PathValue("memberID")
+
+	members, ok := s.members[networkID]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	m, ok := members[memberID]
+	if !ok {
+		m = newMemberObject(networkID, memberID)
+		members[memberID] = m
+	}
+
+	wasAuthorized := m["authorized"].(bool)
+
+	for _, field := range memberFields {
+		if value, ok := body[field]; ok && value != nil {
+			m[field] = value
+		}
+	}
+
+	if authorized := m["authorized"].(bool); authorized && !wasAuthorized {
+		m["lastAuthorizedTime"] = now()
+	} else if !authorized && wasAuthorized {
+		m["lastDeauthorizedTime"] = now()
+	}
+
+	m["revision"] = m["revision"].(float64) + 1
+
+	writeJSON(w, m)
+}
+
+func (s *Server) deleteMember(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	networkID, memberID := r.PathValue("networkID"), r.PathValue("memberID")
+
+	m, ok := s.members[networkID][memberID]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	delete(s.members[networkID], memberID)
+
+	writeJSON(w, m)
+}
+
+//
+// object helpers
+//
+
+func newNetworkObject(id string) object {
+	return object{
+		"id":                id,
+		"nwid":              id,
+		"objtype":           "network",
+		"name":              "",
+		"creationTime":      now(),
+		"revision":          float64(0),
+		"private":           true,
+		"enableBroadcast":   true,
+		"multicastLimit":    float64(32),
+		"mtu":               float64(2800),
+		"v4AssignMode":      object{"zt": false},
+		"v6AssignMode":      object{"zt": false, "6plane": false, "rfc4193": false},
+		"routes":            []interface{}{},
+		"ipAssignmentPools": []interface{}{},
+		"rules":             []interface{}{object{"type": "ACTION_ACCEPT"}},
+		"tags":              []interface{}{},
+		"capabilities":      []interface{}{},
+		"dns":               object{"domain": "", "servers": []interface{}{}},
+		"remoteTraceTarget": nil,
+		"remoteTraceLevel":  float64(0),
+	}
+}
+
+func newMemberObject(networkID, memberID string) object {
+	return object{
+		"id":                   memberID,
+		"address":              memberID,
+		"nwid":                 networkID,
+		"objtype":              "member",
+		"authorized":           false,
+		"activeBridge":         false,
+		"noAutoAssignIps":      false,
+		"ssoExempt":            false,
+		"ipAssignments":        []interface{}{},
+		"tags":                 []interface{}{},
+		"capabilities":         []interface{}{},
+		"creationTime":         now(),
+		"identity":             "",
+		"lastAuthorizedTime":   float64(0),
+		"lastDeauthorizedTime": float64(0),
+		"revision":             float64(0),
+		"remoteTraceTarget":    nil,
+		"remoteTraceLevel":     float64(0),
+		"vMajor":               float64(-1),
+		"vMinor":               float64(-1),
+		"vRev":                 float64(-1),
+		"vProto":               float64(-1),
+	}
+}
+
+func copyObject(obj object) map[string]interface{} {
+	if obj == nil {
+		return nil
+	}
+
+	content, err := json.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(content, &res); err != nil {
+		panic(err)
+	}
+
+	return res
+}
+
+func now() float64 {
+	return float64(time.Now().UnixMilli())
+}
+
+func writeJSON(w http.ResponseWriter, i interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(i)
+}
+
+func writeError(w http.ResponseWriter, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(object{})
+}
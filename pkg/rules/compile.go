@@ -0,0 +1,901 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxIncludeDepth limits how deeply macros may include each other, which also
+// catches a macro that includes itself.
+const maxIncludeDepth = 16
+
+type macro struct {
+	name   string
+	params []string
+	body   []statement
+}
+
+// capBlock is a capability waiting for its rules to be compiled.
+type capBlock struct {
+	capability *Capability
+	body       []statement
+}
+
+type compiler struct {
+	program *Program
+	macros  map[string]*macro
+}
+
+// scope is where a statement is compiled: at the top level, in a capability,
+// or in a macro included with some arguments.
+type scope struct {
+	vars  map[string]string
+	depth int
+}
+
+func (c *compiler) compile(statements []statement) error {
+	var (
+		rules  []statement
+		caps   []capBlock
+		tagIDs = map[uint32]string{}
+		capIDs = map[uint32]string{}
+	)
+
+	// definitions come first, so they can be used anywhere.
+	for i := 0; i < len(statements); i++ {
+		s := statements[i]
+
+		// stray semicolons are harmless.
+		if len(s.tokens) == 0 {
+			continue
+		}
+
+		first := s.tokens[0]
+
+		switch first.text {
+		case "tag":
+			name, tag, err := c.parseTag(s)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := c.program.Tags[name]; ok {
+				return s.tokens[1].errorf("tag %q is already defined", name)
+			}
+			if other, ok := tagIDs[tag.ID]; ok {
+				return s.tokens[1].errorf("tag %q has the same id as tag %q", name, other)
+			}
+
+			c.program.Tags[name] = tag
+			tagIDs[tag.ID] = name
+		case "cap", "macro":
+			body, next, err := block(statements, i)
+			if err != nil {
+				return err
+			}
+			i = next
+
+			if first.text == "cap" {
+				name, capability, rest, err := parseCapHeader(s)
+				if err != nil {
+					return err
+				}
+
+				if _, ok := c.program.Capabilities[name]; ok {
+					return s.tokens[1].errorf("cap %q is already defined", name)
+				}
+				if other, ok := capIDs[capability.ID]; ok {
+					return s.tokens[1].errorf("cap %q has the same id as cap %q", name, other)
+				}
+
+				c.program.Capabilities[name] = capability
+				capIDs[capability.ID] = name
+				caps = append(caps, capBlock{capability: capability, body: prepend(rest, body)})
+			} else {
+				m, rest, err := parseMacroHeader(s)
+				if err != nil {
+					return err
+				}
+
+				if _, ok := c.macros[m.name]; ok {
+					return s.tokens[1].errorf("macro %q is already defined", m.name)
+				}
+
+				m.body = prepend(rest, body)
+				c.macros[m.name] = m
+			}
+		default:
+			rules = append(rules, s)
+		}
+	}
+
+	for _, s := range rules {
+		compiled, err := c.compileStatement(s, &scope{})
+		if err != nil {
+			return err
+		}
+
+		c.program.Rules = append(c.program.Rules, compiled...)
+	}
+
+	for _, cb := range caps {
+		for _, s := range cb.body {
+			compiled, err := c.compileStatement(s, &scope{})
+			if err != nil {
+				return err
+			}
+
+			cb.capability.Rules = append(cb.capability.Rules, compiled...)
+		}
+	}
+
+	return nil
+}
+
+// block returns the statements of the cap or macro opened by statements[i],
+// up to the empty statement that closes it, and the index of that statement.
+func block(statements []statement, i int) ([]statement, int, error) {
+	open := statements[i].tokens[0]
+
+	for j := i + 1; j < len(statements); j++ {
+		s := statements[j]
+		if len(s.tokens) == 0 {
+			return statements[i+1 : j], j, nil
+		}
+
+		switch s.tokens[0].text {
+		case "tag", "cap", "macro":
+			return nil, 0, s.tokens[0].errorf("%s cannot be defined inside the %s at line %d; is the ; that closes it missing?", s.tokens[0].text, open.text, open.line)
+		}
+	}
+
+	return nil, 0, open.errorf("%s is missing the ; that closes it", open.text)
+}
+
+// prepend returns body with the rest of a block's first statement, if there
+// is any, in front of it.
+func prepend(rest *statement, body []statement) []statement {
+	if rest == nil {
+		return body
+	}
+
+	return append([]statement{*rest}, body...)
+}
+
+// remainder returns the tokens of s from i on as a statement, or nil if there
+// are none.
+func remainder(s statement, i int) *statement {
+	if i >= len(s.tokens) {
+		return nil
+	}
+
+	return &statement{tokens: s.tokens[i:], end: s.end}
+}
+
+func (c *compiler) parseTag(s statement) (string, *Tag, error) {
+	name, err := parseName(s, 1, "tag")
+	if err != nil {
+		return "", nil, err
+	}
+
+	tag := &Tag{Enums: map[string]uint32{}, Flags: map[string]uint32{}}
+	var id, def *token
+
+	for i := 2; i < len(s.tokens); i++ {
+		keyword := s.tokens[i]
+
+		args := 1
+		switch keyword.text {
+		case "id", "default":
+		case "enum", "flag":
+			args = 2
+		default:
+			return "", nil, keyword.errorf("unexpected %q in tag %q, expected id, default, enum or flag", keyword.text, name)
+		}
+
+		if i+args >= len(s.tokens) {
+			return "", nil, keyword.errorf("%s in tag %q is missing its value", keyword.text, name)
+		}
+
+		value := s.tokens[i+1]
+
+		switch keyword.text {
+		case "id":
+			n, err := parseNumber(value, math.MaxUint32)
+			if err != nil {
+				return "", nil, err
+			}
+
+			tag.ID = uint32(n)
+			id = &value
+		case "default":
+			def = &value
+		case "enum", "flag":
+			max := uint64(math.MaxUint32)
+			if keyword.text == "flag" {
+				max = 31
+			}
+
+			n, err := parseNumber(value, max)
+			if err != nil {
+				return "", nil, err
+			}
+
+			label := s.tokens[i+2]
+			if err := validName(label); err != nil {
+				return "", nil, err
+			}
+
+			if _, ok := tag.Enums[label.text]; ok {
+				return "", nil, label.errorf("%q is already a value of tag %q", label.text, name)
+			}
+			if _, ok := tag.Flags[label.text]; ok {
+				return "", nil, label.errorf("%q is already a value of tag %q", label.text, name)
+			}
+
+			if keyword.text == "enum" {
+				tag.Enums[label.text] = uint32(n)
+			} else {
+				tag.Flags[label.text] = uint32(n)
+			}
+		}
+
+		i += args
+	}
+
+	if id == nil {
+		return "", nil, s.tokens[1].errorf("tag %q needs an id, such as: tag %s id 1000", name, name)
+	}
+
+	if def != nil {
+		n, err := tagValue(*def, name, tag)
+		if err != nil {
+			return "", nil, err
+		}
+
+		tag.Default = &n
+	}
+
+	return name, tag, nil
+}
+
+func parseCapHeader(s statement) (string, *Capability, *statement, error) {
+	name, err := parseName(s, 1, "cap")
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if len(s.tokens) < 4 || s.tokens[2].text != "id" {
+		return "", nil, nil, s.tokens[1].errorf("cap %q needs an id, such as: cap %s id 1000", name, name)
+	}
+
+	id, err := parseNumber(s.tokens[3], math.MaxUint32)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return name, &Capability{ID: uint32(id), Rules: []Rule{}}, remainder(s, 4), nil
+}
+
+func parseMacroHeader(s statement) (*macro, *statement, error) {
+	if len(s.tokens) < 2 {
+		return nil, nil, s.tokens[0].errorf("macro is missing its name")
+	}
+
+	name, params, err := parseCall(s.tokens[1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, param := range params {
+		if !strings.HasPrefix(param, "$") || len(param) < 2 {
+			return nil, nil, s.tokens[1].errorf("parameter %q of macro %q must start with $", param, name)
+		}
+		if seen[param] {
+			return nil, nil, s.tokens[1].errorf("macro %q has parameter %s twice", name, param)
+		}
+		seen[param] = true
+	}
+
+	return &macro{name: name, params: params}, remainder(s, 2), nil
+}
+
+// parseCall splits name(arg,arg) into its parts. The parentheses are
+// optional when there are no arguments.
+func parseCall(t token) (string, []string, error) {
+	name, args, ok := strings.Cut(t.text, "(")
+	if !ok {
+		return name, nil, validName(t)
+	}
+
+	if !strings.HasSuffix(args, ")") {
+		return "", nil, t.errorf("unexpected text after ) in %q", t.text)
+	}
+
+	if err := validName(token{text: name, line: t.line, column: t.column}); err != nil {
+		return "", nil, err
+	}
+
+	args = strings.TrimSuffix(args, ")")
+	if args == "" {
+		return name, nil, nil
+	}
+
+	return name, strings.Split(args, ","), nil
+}
+
+func parseName(s statement, i int, kind string) (string, error) {
+	if i >= len(s.tokens) {
+		return "", s.tokens[0].errorf("%s is missing its name", kind)
+	}
+
+	return s.tokens[i].text, validName(s.tokens[i])
+}
+
+// validName checks t can name a tag, capability, macro, enum or flag.
+func validName(t token) error {
+	if t.text == "" {
+		return t.errorf("missing name")
+	}
+
+	if reserved[t.text] {
+		return t.errorf("%q is a reserved word and cannot be used as a name", t.text)
+	}
+
+	for i, r := range t.text {
+		if i == 0 && unicode.IsDigit(r) {
+			return t.errorf("%q is not a valid name; names cannot start with a digit", t.text)
+		}
+
+		if r != '_' && r != '-' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return t.errorf("%q is not a valid name; names may only contain letters, digits, _, - and .", t.text)
+		}
+	}
+
+	return nil
+}
+
+// compileStatement compiles a rule or an include.
+func (c *compiler) compileStatement(s statement, sc *scope) ([]Rule, error) {
+	first := s.tokens[0]
+
+	if first.text == "include" {
+		return c.include(s, sc)
+	}
+
+	if _, ok := actions[first.text]; ok {
+		return c.compileRule(s, sc)
+	}
+
+	switch first.text {
+	case "tag", "cap", "macro":
+		return nil, first.errorf("%s cannot be defined inside a cap or macro", first.text)
+	}
+
+	if _, ok := matches[first.text]; ok {
+		return nil, first.errorf("rule starts with the match %q; rules start with an action: accept, drop, break, tee, watch, redirect or priority", first.text)
+	}
+
+	return nil, first.errorf("unknown action %q; rules start with accept, drop, break, tee, watch, redirect or priority", first.text)
+}
+
+func (c *compiler) include(s statement, sc *scope) ([]Rule, error) {
+	include := s.tokens[0]
+
+	if len(s.tokens) != 2 {
+		return nil, include.errorf("include takes a single macro, such as: include name(arg1,arg2)")
+	}
+
+	call := s.tokens[1]
+
+	name, args, err := parseCall(call)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := c.macros[name]
+	if !ok {
+		return nil, call.errorf("undefined macro %q", name)
+	}
+
+	if len(args) != len(m.params) {
+		return nil, call.errorf("macro %q takes %d argument(s), got %d", name, len(m.params), len(args))
+	}
+
+	if sc.depth >= maxIncludeDepth {
+		return nil, call.errorf("macros are included too deeply; does %q include itself?", name)
+	}
+
+	inner := &scope{vars: map[string]string{}, depth: sc.depth + 1}
+	for i, arg := range args {
+		value, err := sc.resolve(token{text: arg, line: call.line, column: call.column})
+		if err != nil {
+			return nil, err
+		}
+
+		inner.vars[m.params[i]] = value.text
+	}
+
+	res := []Rule{}
+	for _, body := range m.body {
+		compiled, err := c.compileStatement(body, inner)
+		if err != nil {
+			var cerr *Error
+			if errors.As(err, &cerr) && sc.depth == 0 {
+				cerr.Message += fmt.Sprintf(" (in macro %q included at line %d)", name, include.line)
+			}
+
+			return nil, err
+		}
+
+		res = append(res, compiled...)
+	}
+
+	return res, nil
+}
+
+// resolve replaces a $variable with the value it was given by the include
+// of the current macro.
+func (sc *scope) resolve(t token) (token, error) {
+	if !strings.HasPrefix(t.text, "$") {
+		return t, nil
+	}
+
+	if sc.vars == nil {
+		return t, t.errorf("%s can only be used inside a macro", t.text)
+	}
+
+	value, ok := sc.vars[t.text]
+	if !ok {
+		return t, t.errorf("undefined macro parameter %s", t.text)
+	}
+
+	t.text = value
+	return t, nil
+}
+
+// compileRule compiles an action and the matches that follow it. In the
+// compiled rules the matches come first.
+func (c *compiler) compileRule(s statement, sc *scope) ([]Rule, error) {
+	// macro bodies are compiled once per include; resolve a copy.
+	tokens := append([]token{}, s.tokens...)
+	for i := range tokens {
+		var err error
+		if tokens[i], err = sc.resolve(tokens[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	keyword := tokens[0]
+	action := actions[keyword.text]
+
+	if len(tokens) <= action.args {
+		return nil, keyword.errorf("%s takes %d argument(s)", keyword.text, action.args)
+	}
+
+	args := tokens[1 : 1+action.args]
+	rule := Rule{"type": action.ruleType}
+
+	switch keyword.text {
+	case "priority":
+		n, err := parseNumber(args[0], 7)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["qosBucket"] = n
+	case "tee", "watch":
+		length := uint64(math.MaxUint16)
+		if args[0].text != "-1" {
+			var err error
+			if length, err = parseNumber(args[0], math.MaxUint16); err != nil {
+				return nil, err
+			}
+		}
+
+		address, err := parseAddress(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		rule["length"] = length
+		rule["address"] = address
+		rule["flags"] = 0
+	case "redirect":
+		address, err := parseAddress(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		rule["address"] = address
+		rule["flags"] = 0
+	}
+
+	res, err := c.compileMatches(tokens[1+action.args:], s.end)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(res, rule), nil
+}
+
+func (c *compiler) compileMatches(tokens []token, end token) ([]Rule, error) {
+	res := []Rule{}
+
+	var (
+		not, or bool
+		// last is the token that must be followed by a match, if any.
+		last *token
+	)
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		switch t.text {
+		case "not":
+			not = !not
+			last = &tokens[i]
+			continue
+		case "and", "or":
+			if len(res) == 0 || last != nil {
+				return nil, t.errorf("unexpected %q; expected a match", t.text)
+			}
+
+			or = t.text == "or"
+			last = &tokens[i]
+			continue
+		}
+
+		match, ok := matches[t.text]
+		if !ok {
+			if _, ok := actions[t.text]; ok {
+				return nil, t.errorf("unexpected action %q; is the ; ending the previous rule missing?", t.text)
+			}
+
+			return nil, t.errorf("unknown match %q", t.text)
+		}
+
+		if i+match.args >= len(tokens) {
+			return nil, t.errorf("%s takes %d argument(s)", t.text, match.args)
+		}
+
+		rule, err := c.compileMatch(t, tokens[i+1:i+1+match.args])
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := rule["type"]; !ok {
+			rule["type"] = match.ruleType
+		}
+		rule["not"] = not
+		rule["or"] = or
+		res = append(res, rule)
+
+		not, or, last = false, false, nil
+		i += match.args
+	}
+
+	if last != nil {
+		return nil, last.errorf("%q must be followed by a match", last.text)
+	}
+
+	return res, nil
+}
+
+func (c *compiler) compileMatch(keyword token, args []token) (Rule, error) {
+	rule := Rule{}
+	arg := args[0]
+
+	switch keyword.text {
+	case "ztsrc", "ztdest":
+		address, err := parseAddress(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["zt"] = address
+	case "vlan":
+		n, err := parseNumber(arg, 4095)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["vlanId"] = n
+	case "vlanpcp":
+		n, err := parseNumber(arg, 7)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["vlanPcp"] = n
+	case "vlandei":
+		n, err := parseNumber(arg, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["vlanDei"] = n
+	case "ethertype":
+		n, err := parseNamedNumber(arg, etherTypes, math.MaxUint16)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["etherType"] = n
+	case "macsrc", "macdest":
+		mac, err := parseMAC(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["mac"] = mac
+	case "ipsrc", "ipdest":
+		prefix, err := parsePrefix(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		direction := "SOURCE"
+		if keyword.text == "ipdest" {
+			direction = "DEST"
+		}
+
+		version := "IPV4"
+		if prefix.Addr().Is6() {
+			version = "IPV6"
+		}
+
+		rule["type"] = fmt.Sprintf("MATCH_%s_%s", version, direction)
+		rule["ip"] = prefix.String()
+	case "iptos":
+		mask, err := parseNumber(arg, math.MaxUint8)
+		if err != nil {
+			return nil, err
+		}
+
+		start, end, err := parseRange(args[1], math.MaxUint8)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["mask"] = mask
+		rule["start"] = start
+		rule["end"] = end
+	case "ipprotocol":
+		n, err := parseNamedNumber(arg, ipProtocols, math.MaxUint8)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["ipProtocol"] = n
+	case "icmp":
+		icmpType, err := parseNumber(arg, math.MaxUint8)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["icmpType"] = icmpType
+		rule["icmpCode"] = nil
+
+		if args[1].text != "-1" {
+			code, err := parseNumber(args[1], math.MaxUint8)
+			if err != nil {
+				return nil, err
+			}
+
+			rule["icmpCode"] = code
+		}
+	case "sport", "dport", "framesize":
+		start, end, err := parseRange(arg, math.MaxUint16)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["start"] = start
+		rule["end"] = end
+	case "chr":
+		var mask uint64
+		for _, name := range strings.Split(arg.text, ",") {
+			bit, ok := characteristics[name]
+			if !ok {
+				return nil, arg.errorf("unknown characteristic %q, expected one of %s", name, strings.Join(sortedKeys(characteristics), ", "))
+			}
+
+			mask |= 1 << bit
+		}
+
+		rule["mask"] = fmt.Sprintf("%016x", mask)
+	case "random":
+		p, err := strconv.ParseFloat(arg.text, 64)
+		if err != nil || p < 0 || p > 1 {
+			return nil, arg.errorf("%q is not a probability between 0 and 1", arg.text)
+		}
+
+		rule["probability"] = uint32(math.Floor(p * math.MaxUint32))
+	default:
+		// the tag matches.
+		id, tag, err := c.tagRef(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := tagValue(args[1], arg.text, tag)
+		if err != nil {
+			return nil, err
+		}
+
+		rule["id"] = id
+		rule["value"] = value
+	}
+
+	return rule, nil
+}
+
+// tagRef returns the id of a tag given by name or number, and the tag if it
+// was defined by name.
+func (c *compiler) tagRef(t token) (uint32, *Tag, error) {
+	if tag, ok := c.program.Tags[t.text]; ok {
+		return tag.ID, tag, nil
+	}
+
+	if len(t.text) > 0 && unicode.IsDigit(rune(t.text[0])) {
+		n, err := parseNumber(t, math.MaxUint32)
+		return uint32(n), nil, err
+	}
+
+	return 0, nil, t.errorf("undefined tag %q", t.text)
+}
+
+// tagValue parses a number, or the name of one of tag's enums or flags.
+func tagValue(t token, name string, tag *Tag) (uint32, error) {
+	if tag != nil {
+		if n, ok := tag.Enums[t.text]; ok {
+			return n, nil
+		}
+		if bit, ok := tag.Flags[t.text]; ok {
+			return 1 << bit, nil
+		}
+	}
+
+	if len(t.text) > 0 && !unicode.IsDigit(rune(t.text[0])) {
+		return 0, t.errorf("%q is not a number or a value of tag %q", t.text, name)
+	}
+
+	n, err := parseNumber(t, math.MaxUint32)
+	return uint32(n), err
+}
+
+// parseNumber parses a decimal, 0x hexadecimal or 0b binary number no larger
+// than max.
+func parseNumber(t token, max uint64) (uint64, error) {
+	text, base := t.text, 10
+
+	switch {
+	case strings.HasPrefix(text, "0x"):
+		text, base = text[2:], 16
+	case strings.HasPrefix(text, "0b"):
+		text, base = text[2:], 2
+	}
+
+	n, err := strconv.ParseUint(text, base, 64)
+	if err != nil {
+		return 0, t.errorf("%q is not a number", t.text)
+	}
+
+	if n > max {
+		return 0, t.errorf("%s is out of range, must be between 0 and %d", t.text, max)
+	}
+
+	return n, nil
+}
+
+func parseNamedNumber(t token, names map[string]uint64, max uint64) (uint64, error) {
+	if n, ok := names[t.text]; ok {
+		return n, nil
+	}
+
+	if len(t.text) > 0 && !unicode.IsDigit(rune(t.text[0])) {
+		return 0, t.errorf("%q is not a number or one of %s", t.text, strings.Join(sortedKeys(names), ", "))
+	}
+
+	return parseNumber(t, max)
+}
+
+// parseRange parses a single number or an inclusive range such as 80-443.
+func parseRange(t token, max uint64) (uint64, uint64, error) {
+	first, last, ok := strings.Cut(t.text, "-")
+
+	start, err := parseNumber(token{text: first, line: t.line, column: t.column}, max)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if !ok {
+		return start, start, nil
+	}
+
+	end, err := parseNumber(token{text: last, line: t.line, column: t.column + len(first) + 1}, max)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if start > end {
+		return 0, 0, t.errorf("range %s ends before it starts", t.text)
+	}
+
+	return start, end, nil
+}
+
+// parseAddress parses a 10 digit ZeroTier address.
+func parseAddress(t token) (string, error) {
+	address := strings.ToLower(t.text)
+
+	if len(address) != 10 || strings.Trim(address, "0123456789abcdef") != "" {
+		return "", t.errorf("%q is not a ZeroTier address; expected 10 hexadecimal digits", t.text)
+	}
+
+	return address, nil
+}
+
+// parseMAC parses a MAC address with any or no separators between the
+// digits.
+func parseMAC(t token) (string, error) {
+	var digits []rune
+	for _, r := range strings.ToLower(t.text) {
+		switch {
+		case strings.ContainsRune("0123456789abcdef", r):
+			digits = append(digits, r)
+		case strings.ContainsRune(":-.", r):
+		default:
+			return "", t.errorf("%q is not a MAC address", t.text)
+		}
+	}
+
+	if len(digits) != 12 {
+		return "", t.errorf("%q is not a MAC address", t.text)
+	}
+
+	octets := []string{}
+	for i := 0; i < 12; i += 2 {
+		octets = append(octets, string(digits[i:i+2]))
+	}
+
+	return strings.Join(octets, ":"), nil
+}
+
+// parsePrefix parses an address with an optional prefix length, which
+// defaults to the whole address.
+func parsePrefix(t token) (netip.Prefix, error) {
+	if strings.Contains(t.text, "/") {
+		prefix, err := netip.ParsePrefix(t.text)
+		if err != nil {
+			return netip.Prefix{}, t.errorf("%q is not an IP address or network", t.text)
+		}
+
+		return prefix, nil
+	}
+
+	addr, err := netip.ParseAddr(t.text)
+	if err != nil {
+		return netip.Prefix{}, t.errorf("%q is not an IP address or network", t.text)
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,46 @@
+package rules
+
+import "strings"
+
+// Format returns the canonical form of src: comments are dropped, each
+// statement is put on its own line with its words separated by single
+// spaces, statements inside a cap or macro are indented, and stray
+// semicolons outside of them are removed. Sources that differ only in layout
+// format the same, so Format can be used to compare them. The source is
+// not compiled; the error, if any, is an *Error for a word that can't be
+// split from the rest.
+func Format(src string) (string, error) {
+	statements, err := tokenize(src)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		b       strings.Builder
+		inBlock bool
+	)
+
+	for _, s := range statements {
+		words := make([]string, len(s.tokens))
+		for j, t := range s.tokens {
+			words[j] = t.text
+		}
+
+		switch {
+		case len(words) == 0 && !inBlock:
+			continue
+		case len(words) == 0:
+			// the empty statement closing a block.
+			inBlock = false
+		case inBlock:
+			b.WriteString("  ")
+		case words[0] == "cap" || words[0] == "macro":
+			inBlock = true
+		}
+
+		b.WriteString(strings.Join(words, " "))
+		b.WriteString(";\n")
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,138 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	tests := map[string]struct {
+		src      string
+		expected string
+	}{
+		"empty": {
+			src:      "",
+			expected: "",
+		},
+		"only comments": {
+			src:      "# nothing here\n  # or here\n",
+			expected: "",
+		},
+		"canonical": {
+			src:      "accept;\n",
+			expected: "accept;\n",
+		},
+		"missing final semicolon": {
+			src:      "drop not ethertype ipv4\n",
+			expected: "drop not ethertype ipv4;\n",
+		},
+		"no final newline": {
+			src:      "accept;",
+			expected: "accept;\n",
+		},
+		"crlf": {
+			src:      "drop\r\n  not ethertype ipv4\r\n;\r\naccept;\r\n",
+			expected: "drop not ethertype ipv4;\naccept;\n",
+		},
+		"tabs and runs of spaces": {
+			src:      "\tdrop  not\tethertype    ipv4 ;",
+			expected: "drop not ethertype ipv4;\n",
+		},
+		"statements on one line": {
+			src:      "drop chr inbound; accept;",
+			expected: "drop chr inbound;\naccept;\n",
+		},
+		"statement over several lines": {
+			src: `drop
+  not ethertype ipv4
+  and not ethertype arp
+;`,
+			expected: "drop not ethertype ipv4 and not ethertype arp;\n",
+		},
+		"trailing comments": {
+			src:      "drop chr inbound; # no inbound\naccept; # the rest\n",
+			expected: "drop chr inbound;\naccept;\n",
+		},
+		"comment inside a statement": {
+			src:      "drop # for now\n  not ethertype ipv4;\n",
+			expected: "drop not ethertype ipv4;\n",
+		},
+		"comment without whitespace": {
+			src:      "accept;#done",
+			expected: "accept;\n",
+		},
+		"stray semicolons": {
+			src:      ";;drop chr inbound;;\n;\naccept;;",
+			expected: "drop chr inbound;\naccept;\n",
+		},
+		"cap": {
+			src: `cap superuser id 1000
+  accept;
+;`,
+			expected: "cap superuser id 1000 accept;\n;\n",
+		},
+		"cap with several rules": {
+			src:      "cap ops id 1 drop chr inbound;accept ; ;accept;",
+			expected: "cap ops id 1 drop chr inbound;\n  accept;\n;\naccept;\n",
+		},
+		"cap without rules": {
+			src:      "cap nothing id 2;\n\n;\n",
+			expected: "cap nothing id 2;\n;\n",
+		},
+		"macro parameters": {
+			src:      "macro allow( $a ,\n $b )\n  accept dport $a or dport $b;\n;\ninclude allow( 22, 80 );",
+			expected: "macro allow($a,$b) accept dport $a or dport $b;\n;\ninclude allow(22,80);\n",
+		},
+		"case is kept": {
+			src:      "tag Dept id 1 enum 1 Sales;",
+			expected: "tag Dept id 1 enum 1 Sales;\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual, err := Format(test.src)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+
+			again, err := Format(actual)
+			assert.NoError(t, err)
+			assert.Equal(t, actual, again, "formatting is not idempotent")
+		})
+	}
+}
+
+func TestFormat_Errors(t *testing.T) {
+	_, err := Format("include allow(22;")
+	assert.EqualError(t, err, `line 1, column 9: missing ) in "allow(22"`)
+}
+
+// TestFormat_Corpus checks that formatting doesn't change what the corpus
+// compiles to.
+func TestFormat_Corpus(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.rules")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			assert.NoError(t, err)
+
+			formatted, err := Format(string(src))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			expected, err := Compile(string(src))
+			assert.NoError(t, err)
+
+			actual, err := Compile(formatted)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
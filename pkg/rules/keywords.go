@@ -0,0 +1,124 @@
+package rules
+
+// actions maps each action keyword to its rule type and the number of
+// arguments it takes.
+var actions = map[string]struct {
+	ruleType string
+	args     int
+}{
+	"accept":   {"ACTION_ACCEPT", 0},
+	"drop":     {"ACTION_DROP", 0},
+	"break":    {"ACTION_BREAK", 0},
+	"priority": {"ACTION_PRIORITY", 1},
+	"tee":      {"ACTION_TEE", 2},
+	"watch":    {"ACTION_WATCH", 2},
+	"redirect": {"ACTION_REDIRECT", 1},
+}
+
+// matches maps each match keyword to its rule type and the number of
+// arguments it takes. ipsrc and ipdest have a v4 and a v6 type, picked by
+// the address they are given.
+var matches = map[string]struct {
+	ruleType string
+	args     int
+}{
+	"ztsrc":      {"MATCH_SOURCE_ZEROTIER_ADDRESS", 1},
+	"ztdest":     {"MATCH_DEST_ZEROTIER_ADDRESS", 1},
+	"vlan":       {"MATCH_VLAN_ID", 1},
+	"vlanpcp":    {"MATCH_VLAN_PCP", 1},
+	"vlandei":    {"MATCH_VLAN_DEI", 1},
+	"ethertype":  {"MATCH_ETHERTYPE", 1},
+	"macsrc":     {"MATCH_MAC_SOURCE", 1},
+	"macdest":    {"MATCH_MAC_DEST", 1},
+	"ipsrc":      {"MATCH_IPV4_SOURCE", 1},
+	"ipdest":     {"MATCH_IPV4_DEST", 1},
+	"iptos":      {"MATCH_IP_TOS", 2},
+	"ipprotocol": {"MATCH_IP_PROTOCOL", 1},
+	"icmp":       {"MATCH_ICMP", 2},
+	"sport":      {"MATCH_IP_SOURCE_PORT_RANGE", 1},
+	"dport":      {"MATCH_IP_DEST_PORT_RANGE", 1},
+	"chr":        {"MATCH_CHARACTERISTICS", 1},
+	"framesize":  {"MATCH_FRAME_SIZE_RANGE", 1},
+	"random":     {"MATCH_RANDOM", 1},
+	"tdiff":      {"MATCH_TAGS_DIFFERENCE", 2},
+	"tand":       {"MATCH_TAGS_BITWISE_AND", 2},
+	"tor":        {"MATCH_TAGS_BITWISE_OR", 2},
+	"txor":       {"MATCH_TAGS_BITWISE_XOR", 2},
+	"teq":        {"MATCH_TAGS_EQUAL", 2},
+	"tseq":       {"MATCH_TAG_SENDER", 2},
+	"treq":       {"MATCH_TAG_RECEIVER", 2},
+}
+
+// reserved words can't be used as the names of tags, capabilities, macros,
+// enums or flags.
+var reserved = map[string]bool{
+	"null": true, "true": true, "false": true,
+	"type": true, "enum": true, "class": true, "define": true, "import": true,
+	"include": true, "log": true, "not": true, "xor": true, "or": true,
+	"and": true, "set": true, "var": true, "let": true,
+	"tag": true, "cap": true, "macro": true, "id": true, "default": true, "flag": true,
+}
+
+func init() {
+	for keyword := range actions {
+		reserved[keyword] = true
+	}
+	for keyword := range matches {
+		reserved[keyword] = true
+	}
+}
+
+// characteristics are the bits matched by chr.
+var characteristics = map[string]uint{
+	"inbound":   63,
+	"multicast": 62,
+	"broadcast": 61,
+	"ipauth":    60,
+	"macauth":   59,
+	"tcp_fin":   0,
+	"tcp_syn":   1,
+	"tcp_rst":   2,
+	"tcp_psh":   3,
+	"tcp_ack":   4,
+	"tcp_urg":   5,
+	"tcp_ece":   6,
+	"tcp_cwr":   7,
+	"tcp_ns":    8,
+	"tcp_rs2":   9,
+	"tcp_rs1":   10,
+	"tcp_rs0":   11,
+}
+
+// etherTypes are the names accepted by ethertype.
+var etherTypes = map[string]uint64{
+	"ipv4":  0x0800,
+	"arp":   0x0806,
+	"wol":   0x0842,
+	"rarp":  0x8035,
+	"ipv6":  0x86dd,
+	"atalk": 0x809b,
+	"aarp":  0x80f3,
+	"ipx_a": 0x8137,
+	"ipx_b": 0x8138,
+}
+
+// ipProtocols are the names accepted by ipprotocol.
+var ipProtocols = map[string]uint64{
+	"icmp":    0x01,
+	"icmp4":   0x01,
+	"icmpv4":  0x01,
+	"igmp":    0x02,
+	"ipip":    0x04,
+	"tcp":     0x06,
+	"egp":     0x08,
+	"igp":     0x09,
+	"udp":     0x11,
+	"rdp":     0x1b,
+	"esp":     0x32,
+	"ah":      0x33,
+	"icmp6":   0x3a,
+	"icmpv6":  0x3a,
+	"l2tp":    0x73,
+	"sctp":    0x84,
+	"udplite": 0x88,
+}
@@ -0,0 +1,96 @@
+// Package rules parses and compiles the ZeroTier flow rules language into the
+// rule, capability and tag objects understood by network controllers. It
+// follows the behavior of the rule compiler used by ZeroTier Central, so that
+// mistakes in a network's flow_rules can be found before they are sent.
+//
+// A program is a list of statements, each terminated by a semicolon:
+//
+//	# actions are followed by the matches they apply to
+//	drop not ethertype ipv4 and not ethertype arp;
+//
+//	tag department id 1000 enum 100 sales enum 200 engineering;
+//
+//	cap superuser id 1000
+//	  accept;
+//	;
+//
+//	macro allow_port($port)
+//	  accept ipprotocol tcp and dport $port;
+//	;
+//
+//	include allow_port(22);
+//	accept;
+//
+// Capabilities and macros are blocks of statements closed by an empty
+// statement, so a block without rules needs two semicolons. See
+// https://docs.zerotier.com/rules for the language itself.
+//
+// Format puts a source in a canonical layout, so that sources differing only
+// in whitespace and comments can be told apart from real changes.
+package rules
+
+import "fmt"
+
+// Rule is a single compiled rule entry, a match or an action, as it appears
+// in a network's configuration.
+type Rule = map[string]interface{}
+
+// Program is the result of compiling a rules source.
+type Program struct {
+	// Rules are the network's base rules, in order.
+	Rules []Rule
+	// Capabilities are the capabilities defined with cap, by name.
+	Capabilities map[string]*Capability
+	// Tags are the tags defined with tag, by name.
+	Tags map[string]*Tag
+}
+
+// Capability is a named set of rules that can be granted to members.
+type Capability struct {
+	ID    uint32 `json:"id"`
+	Rules []Rule `json:"rules"`
+}
+
+// Tag is a named value that can be set on members and matched by rules.
+type Tag struct {
+	ID      uint32            `json:"id"`
+	Default *uint32           `json:"default"`
+	Enums   map[string]uint32 `json:"enums"`
+	Flags   map[string]uint32 `json:"flags"`
+}
+
+// Error is a compilation error at a position in the source. Lines and
+// columns start at 1.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Compile compiles src. The error, if any, is an *Error describing the first
+// problem found.
+func Compile(src string) (*Program, error) {
+	statements, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiler{
+		program: &Program{
+			Rules:        []Rule{},
+			Capabilities: map[string]*Capability{},
+			Tags:         map[string]*Tag{},
+		},
+		macros: map[string]*macro{},
+	}
+
+	if err := c.compile(statements); err != nil {
+		return nil, err
+	}
+
+	return c.program, nil
+}
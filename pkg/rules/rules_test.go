@@ -0,0 +1,102 @@
+package rules
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected output of the corpus")
+
+// TestCompile_Corpus compiles every testdata/*.rules file and compares the
+// result with the .json file next to it. Run with -update after changing
+// the compiler to regenerate them, and review the diff.
+func TestCompile_Corpus(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.rules")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			assert.NoError(t, err)
+
+			program, err := Compile(string(src))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			actual, err := json.MarshalIndent(map[string]interface{}{
+				"rules":        program.Rules,
+				"capabilities": program.Capabilities,
+				"tags":         program.Tags,
+			}, "", "  ")
+			assert.NoError(t, err)
+
+			golden := strings.TrimSuffix(file, ".rules") + ".json"
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, append(actual, '\n'), 0644))
+			}
+
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
+
+// TestCompile_Errors compiles every testdata/errors/*.rules file, each of
+// which starts with a "# error: " comment holding the expected error.
+func TestCompile_Errors(t *testing.T) {
+	files, err := filepath.Glob("testdata/errors/*.rules")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			f, err := os.Open(file)
+			assert.NoError(t, err)
+			defer f.Close()
+
+			scanner := bufio.NewScanner(f)
+			scanner.Scan()
+			expected, ok := strings.CutPrefix(scanner.Text(), "# error: ")
+			assert.True(t, ok, "missing # error: comment")
+
+			src, err := os.ReadFile(file)
+			assert.NoError(t, err)
+
+			_, err = Compile(string(src))
+			assert.EqualError(t, err, expected)
+
+			var cerr *Error
+			assert.True(t, errors.As(err, &cerr))
+		})
+	}
+}
+
+func TestCompile_MissingFinalSemicolon(t *testing.T) {
+	withSemicolon, err := Compile("drop not ethertype ipv4;\naccept;")
+	assert.NoError(t, err)
+
+	without, err := Compile("drop not ethertype ipv4;\naccept")
+	assert.NoError(t, err)
+
+	assert.Equal(t, withSemicolon, without)
+}
+
+func TestError_Position(t *testing.T) {
+	_, err := Compile("accept;\n\n  drop   ethertype ipv7;")
+
+	var cerr *Error
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, 3, cerr.Line)
+	assert.Equal(t, 20, cerr.Column)
+}
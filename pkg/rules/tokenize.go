@@ -0,0 +1,116 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// token is a word of the source and where it starts.
+type token struct {
+	text   string
+	line   int
+	column int
+}
+
+func (t token) errorf(format string, args ...interface{}) *Error {
+	return errorAt(t.line, t.column, format, args...)
+}
+
+// statement is the tokens up to a semicolon. end is the semicolon itself, or
+// the end of the source if it was missing.
+type statement struct {
+	tokens []token
+	end    token
+}
+
+// tokenize splits src into statements of whitespace separated words,
+// dropping comments. A word with an open parenthesis, such as a macro's
+// parameter list, continues until it is closed.
+func tokenize(src string) ([]statement, error) {
+	var (
+		statements []statement
+		current    statement
+		word       strings.Builder
+		start      token
+		depth      int
+		comment    bool
+	)
+
+	line, column := 1, 0
+
+	endWord := func() {
+		if word.Len() > 0 {
+			start.text = word.String()
+			current.tokens = append(current.tokens, start)
+			word.Reset()
+		}
+	}
+
+	for _, r := range src {
+		if r == '\n' {
+			line++
+			column = 0
+		} else {
+			column++
+		}
+
+		switch {
+		case comment:
+			if r == '\n' {
+				comment = false
+			}
+		case depth > 0:
+			// inside parentheses, whitespace is dropped and only the
+			// closing parenthesis is special.
+			switch {
+			case r == ')':
+				depth--
+				word.WriteRune(r)
+			case r == '(':
+				depth++
+				word.WriteRune(r)
+			case r == ';' || r == '#':
+				return nil, start.errorf("missing ) in %q", word.String())
+			case !unicode.IsSpace(r):
+				word.WriteRune(r)
+			}
+		case r == '#':
+			endWord()
+			comment = true
+		case r == ';':
+			endWord()
+			current.end = token{text: ";", line: line, column: column}
+			statements = append(statements, current)
+			current = statement{}
+		case unicode.IsSpace(r):
+			endWord()
+		default:
+			if word.Len() == 0 {
+				start = token{line: line, column: column}
+			}
+			if r == '(' {
+				depth++
+			}
+			word.WriteRune(r)
+		}
+	}
+
+	if depth > 0 {
+		return nil, start.errorf("missing ) in %q", word.String())
+	}
+
+	endWord()
+
+	// a missing semicolon at the very end is forgiven.
+	if len(current.tokens) > 0 {
+		current.end = token{text: "", line: line, column: column + 1}
+		statements = append(statements, current)
+	}
+
+	return statements, nil
+}
+
+func errorAt(line, column int, format string, args ...interface{}) *Error {
+	return &Error{Line: line, Column: column, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,270 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+// resourceNetworkCustomizeAddresses checks that routes and assignment pools
+// are valid and agree with each other, which Central only partly does.
+// Routes and pools are sets, so problems name them by their value; subnets
+// are named by their index. The planned route targets are noted for the
+// network's members.
+func resourceNetworkCustomizeAddresses(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	cache, _ := m.(*networkCache)
+	if d.Id() == "" {
+		cache = nil
+	}
+
+	if !wholeValueKnown(d, "route") || !wholeValueKnown(d, "subnet") {
+		if cache != nil {
+			cache.noteRouteTargets(d.Id(), nil)
+		}
+
+		return nil
+	}
+
+	subnetRoutes, subnetPools, err := mkSubnets(d.Get("subnet"))
+	if err != nil {
+		return err
+	}
+
+	routes, diags := mkRoutes(d.Get("route"))
+	if diags.HasError() {
+		return fmt.Errorf("route: %s", diags[0].Summary)
+	}
+
+	targets := []netip.Prefix{}
+	for _, route := range *routes.(*[]spec.Route) {
+		target, err := parseRoute("route", route)
+		if err != nil {
+			return err
+		}
+
+		targets = append(targets, target)
+	}
+
+	for i, route := range subnetRoutes {
+		target, err := parseRoute(fmt.Sprintf("subnet.%d", i), route)
+		if err != nil {
+			return err
+		}
+
+		targets = append(targets, target)
+	}
+
+	if cache != nil {
+		cache.noteRouteTargets(d.Id(), targets)
+	}
+
+	if !wholeValueKnown(d, "assignment_pool") {
+		return nil
+	}
+
+	pools, diags := mkIPRange(d.Get("assignment_pool"))
+	if diags.HasError() {
+		return fmt.Errorf("assignment_pool: %s", diags[0].Summary)
+	}
+
+	for _, pool := range *pools.(*[]spec.IPRange) {
+		if err := checkAssignmentPool("assignment_pool", pool, targets); err != nil {
+			return err
+		}
+	}
+
+	for i, pool := range subnetPools {
+		if err := checkAssignmentPool(fmt.Sprintf("subnet.%d", i), pool, targets); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resourceMemberCustomizeAddresses checks that ip_assignments are inside the
+// routes the plan gives the member's network, or its current routes if the
+// network isn't part of the plan. When the network or its routes are not
+// known until applying, checkMemberAddresses checks them then.
+func resourceMemberCustomizeAddresses(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.HasChange("ip_assignments") || !wholeValueKnown(d, "ip_assignments") || !d.NewValueKnown("network_id") {
+		return nil
+	}
+
+	ips := []string{}
+	for _, ip := range d.Get("ip_assignments").(*schema.Set).List() {
+		ips = append(ips, ip.(string))
+	}
+
+	if len(ips) == 0 {
+		return nil
+	}
+
+	nwid := d.Get("network_id").(string)
+
+	if cache, ok := m.(*networkCache); ok {
+		if targets, noted := cache.plannedRouteTargets(nwid); noted {
+			if targets == nil {
+				return nil
+			}
+
+			return checkMemberIPs(ips, targets)
+		}
+	}
+
+	targets, err := memberRouteTargets(ctx, m.(backend), nwid)
+	if err != nil {
+		return err
+	}
+
+	return checkMemberIPs(ips, targets)
+}
+
+// checkMemberAddresses checks that the IP assignments of member are inside
+// the routes of its network, for members whose plan couldn't check them.
+func checkMemberAddresses(ctx context.Context, c backend, member *spec.Member) diag.Diagnostics {
+	if member.Config == nil || member.Config.IpAssignments == nil || len(*member.Config.IpAssignments) == 0 {
+		return nil
+	}
+
+	targets, err := memberRouteTargets(ctx, c, *member.NetworkId)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := checkMemberIPs(*member.Config.IpAssignments, targets); err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid ZeroTier Member IP assignment",
+			Detail:        err.Error(),
+			AttributePath: cty.GetAttrPath("ip_assignments"),
+		}}
+	}
+
+	return nil
+}
+
+// wholeValueKnown reports whether the planned value of key is known,
+// including the attributes of its blocks.
+func wholeValueKnown(d *schema.ResourceDiff, key string) bool {
+	return d.NewValueKnown(key) && d.GetRawConfig().GetAttr(key).IsWhollyKnown()
+}
+
+// memberRouteTargets returns the route targets of the network nwid as it is
+// now.
+func memberRouteTargets(ctx context.Context, c backend, nwid string) ([]netip.Prefix, error) {
+	n, err := memberNetwork(ctx, c, nwid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the routes of network %s: %w", nwid, err)
+	}
+
+	targets := []netip.Prefix{}
+	if n.Config != nil && n.Config.Routes != nil {
+		for _, route := range *n.Config.Routes {
+			target, err := parseRoute("route", route)
+			if err != nil {
+				return nil, fmt.Errorf("network %s: %w", nwid, err)
+			}
+
+			targets = append(targets, target)
+		}
+	}
+
+	return targets, nil
+}
+
+// parseRoute checks the target and gateway of a route of block, which is
+// route or a subnet, and returns the target.
+func parseRoute(block string, route spec.Route) (netip.Prefix, error) {
+	target, err := netip.ParsePrefix(ptrString(route.Target))
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%s: target %q is not a network in CIDR notation", block, ptrString(route.Target))
+	}
+
+	if via := ptrString(route.Via); via != "" {
+		gateway, err := netip.ParseAddr(via)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("%s %s: via %q is not an IP address", block, target, via)
+		}
+
+		if gateway.Is4() != target.Addr().Is4() {
+			return netip.Prefix{}, fmt.Errorf("%s %s: via %s is not in the address family of the target", block, target, gateway)
+		}
+	}
+
+	return target.Masked(), nil
+}
+
+// checkAssignmentPool checks that a pool of block, which is assignment_pool
+// or a subnet, is a range of addresses inside one of the route targets.
+func checkAssignmentPool(block string, pool spec.IPRange, targets []netip.Prefix) error {
+	name := block + " " + ptrString(pool.IpRangeStart) + "-" + ptrString(pool.IpRangeEnd)
+
+	start, err := netip.ParseAddr(ptrString(pool.IpRangeStart))
+	if err != nil {
+		return fmt.Errorf("%s: start %q is not an IP address", name, ptrString(pool.IpRangeStart))
+	}
+
+	end, err := netip.ParseAddr(ptrString(pool.IpRangeEnd))
+	if err != nil {
+		return fmt.Errorf("%s: end %q is not an IP address", name, ptrString(pool.IpRangeEnd))
+	}
+
+	if start.Is4() != end.Is4() {
+		return fmt.Errorf("%s: start and end are in different address families", name)
+	}
+
+	if start.Compare(end) > 0 {
+		return fmt.Errorf("%s: start is after end", name)
+	}
+
+	for _, target := range targets {
+		if target.Contains(start) && target.Contains(end) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: not inside any route target (%s)", name, prefixList(targets))
+}
+
+// checkMemberIPs checks that each of ips is inside one of the route targets.
+func checkMemberIPs(ips []string, targets []netip.Prefix) error {
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return fmt.Errorf("ip_assignments: %q is not an IP address", ip)
+		}
+
+		inside := false
+		for _, target := range targets {
+			inside = inside || target.Contains(addr)
+		}
+
+		if !inside {
+			return fmt.Errorf("ip_assignments: %s is not inside any of the network's routes (%s)", addr, prefixList(targets))
+		}
+	}
+
+	return nil
+}
+
+func prefixList(prefixes []netip.Prefix) string {
+	if len(prefixes) == 0 {
+		return "none"
+	}
+
+	names := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		names[i] = prefix.String()
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,387 @@
+package zerotier
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+func TestParseRoute(t *testing.T) {
+	tests := map[string]struct {
+		target   string
+		via      string
+		expected string
+		err      string
+	}{
+		"ipv4": {
+			target:   "10.0.0.0/24",
+			expected: "10.0.0.0/24",
+		},
+		"ipv6 with gateway": {
+			target:   "fd00::/64",
+			via:      "fd00::1",
+			expected: "fd00::/64",
+		},
+		"host bits": {
+			target:   "10.0.0.1/24",
+			expected: "10.0.0.0/24",
+		},
+		"not cidr": {
+			target: "10.0.0.0",
+			err:    `route: target "10.0.0.0" is not a network in CIDR notation`,
+		},
+		"bad gateway": {
+			target: "10.0.0.0/24",
+			via:    "gateway",
+			err:    `route 10.0.0.0/24: via "gateway" is not an IP address`,
+		},
+		"gateway family": {
+			target: "10.0.0.0/24",
+			via:    "fd00::1",
+			err:    `route 10.0.0.0/24: via fd00::1 is not in the address family of the target`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			target, err := parseRoute("route", spec.Route{Target: stringPtr(test.target), Via: stringPtr(test.via)})
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, netip.MustParsePrefix(test.expected), target)
+		})
+	}
+}
+
+func TestCheckAssignmentPool(t *testing.T) {
+	targets := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("fd00::/64"),
+	}
+
+	tests := map[string]struct {
+		start string
+		end   string
+		err   string
+	}{
+		"ipv4": {
+			start: "10.0.0.10",
+			end:   "10.0.0.20",
+		},
+		"single address": {
+			start: "10.0.0.10",
+			end:   "10.0.0.10",
+		},
+		"ipv6": {
+			start: "fd00::10",
+			end:   "fd00::ffff",
+		},
+		"reversed": {
+			start: "10.0.0.20",
+			end:   "10.0.0.10",
+			err:   "assignment_pool 10.0.0.20-10.0.0.10: start is after end",
+		},
+		"mixed families": {
+			start: "10.0.0.10",
+			end:   "fd00::10",
+			err:   "assignment_pool 10.0.0.10-fd00::10: start and end are in different address families",
+		},
+		"bad start": {
+			start: "10.0.0",
+			end:   "10.0.0.10",
+			err:   `assignment_pool 10.0.0-10.0.0.10: start "10.0.0" is not an IP address`,
+		},
+		"bad end": {
+			start: "10.0.0.10",
+			end:   "10.0.0.300",
+			err:   `assignment_pool 10.0.0.10-10.0.0.300: end "10.0.0.300" is not an IP address`,
+		},
+		"outside routes": {
+			start: "10.1.0.10",
+			end:   "10.1.0.20",
+			err:   "assignment_pool 10.1.0.10-10.1.0.20: not inside any route target (10.0.0.0/24, fd00::/64)",
+		},
+		"across a route boundary": {
+			start: "10.0.0.10",
+			end:   "10.0.1.10",
+			err:   "assignment_pool 10.0.0.10-10.0.1.10: not inside any route target (10.0.0.0/24, fd00::/64)",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkAssignmentPool("assignment_pool", spec.IPRange{IpRangeStart: stringPtr(test.start), IpRangeEnd: stringPtr(test.end)}, targets)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+
+	err := checkAssignmentPool("subnet.0", spec.IPRange{IpRangeStart: stringPtr("10.0.0.1"), IpRangeEnd: stringPtr("10.0.0.2")}, nil)
+	assert.EqualError(t, err, "subnet.0 10.0.0.1-10.0.0.2: not inside any route target (none)")
+}
+
+func TestCheckMemberIPs(t *testing.T) {
+	targets := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("fd00::/64"),
+	}
+
+	assert.NoError(t, checkMemberIPs([]string{"10.0.0.1", "fd00::1"}, targets))
+	assert.NoError(t, checkMemberIPs(nil, nil))
+	assert.EqualError(t, checkMemberIPs([]string{"10.0.0.1", "10.0.1.1"}, targets), "ip_assignments: 10.0.1.1 is not inside any of the network's routes (10.0.0.0/24, fd00::/64)")
+	assert.EqualError(t, checkMemberIPs([]string{"10.0.0.0/24"}, targets), `ip_assignments: "10.0.0.0/24" is not an IP address`)
+	assert.EqualError(t, checkMemberIPs([]string{"10.0.0.1"}, nil), "ip_assignments: 10.0.0.1 is not inside any of the network's routes (none)")
+}
+
+func TestAccNetwork_invalidAddresses(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-addresses"
+
+  route {
+    target = "10.0.0.0/24"
+    via    = "fd00::1"
+  }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`route 10.0.0.0/24: via fd00::1 is not in the address family of the\s+target`),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-addresses"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+
+  assignment_pool {
+    start = "10.0.0.200"
+    end   = "10.0.0.100"
+  }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`assignment_pool 10.0.0.200-10.0.0.100: start is after end`),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-addresses"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+
+  assignment_pool {
+    start = "10.0.1.1"
+    end   = "10.0.1.100"
+  }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`assignment_pool 10.0.1.1-10.0.1.100: not inside any route target\s+\(10.0.0.0/24\)`),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-addresses"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+
+  route {
+    target = "10.1.0.0/24"
+  }
+
+  route {
+    target = "10.2.0.0/24"
+    via    = "fd00::1"
+  }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`route 10.2.0.0/24: via fd00::1 is not in the address family of the\s+target`),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-addresses"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+
+  route {
+    target = "10.1.0.0/24"
+  }
+
+  assignment_pool {
+    start = "10.0.0.10"
+    end   = "10.0.0.20"
+  }
+
+  assignment_pool {
+    start = "10.9.0.10"
+    end   = "10.9.0.20"
+  }
+
+  assignment_pool {
+    start = "10.1.0.10"
+    end   = "10.1.0.20"
+  }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`assignment_pool 10.9.0.10-10.9.0.20: not inside any route target\s+\(10.0.0.0/24, 10.1.0.0/24\)`),
+			},
+		},
+	})
+
+	assert.Empty(t, srv.NetworkIDs())
+}
+
+func TestAccMember_invalidAddresses(t *testing.T) {
+	srv := testAccCentral(t)
+
+	config := func(routes []string, ips ...string) string {
+		network := `
+resource "zerotier_network" "test" {
+  name = "acc-member-addresses"
+`
+		for _, route := range routes {
+			network += fmt.Sprintf(`
+  route {
+    target = %q
+  }
+`, route)
+		}
+
+		return testAccConfig(srv, network+fmt.Sprintf(`}
+
+resource "zerotier_member" "test" {
+  network_id     = zerotier_network.test.id
+  member_id      = "a1b2c3d4e5"
+  ip_assignments = %s
+}
+`, hclStringList(ips)))
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: config([]string{"10.0.0.0/24"}, "10.0.0.1"),
+				Check:  resource.TestCheckResourceAttr("zerotier_member.test", "ip_assignments.#", "1"),
+			},
+			{
+				Config:      config([]string{"10.0.0.0/24"}, "10.0.0.1", "192.168.1.1"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`ip_assignments: 192.168.1.1 is not inside any of the network's routes\s+\(10.0.0.0/24\)`),
+			},
+			{
+				// a route added in the same apply as an address in it.
+				Config: config([]string{"10.0.0.0/24", "192.168.1.0/24"}, "10.0.0.1", "192.168.1.1"),
+				Check:  resource.TestCheckResourceAttr("zerotier_member.test", "ip_assignments.#", "2"),
+			},
+			{
+				// the addresses are checked against the routes as planned,
+				// not as they are now.
+				Config:      config([]string{"10.0.0.0/24"}, "10.0.0.1", "10.0.0.2", "192.168.1.1"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`ip_assignments: 192.168.1.1 is not inside any of the network's routes\s+\(10.0.0.0/24\)`),
+			},
+		},
+	})
+}
+
+func TestAccMember_addressesUnknownRoutes(t *testing.T) {
+	srv := testAccCentral(t)
+
+	// the route of the network is unknown until the other network exists.
+	config := func(ip string) string {
+		return testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_network" "other" {
+  name = "acc-member-addresses-other"
+}
+
+resource "zerotier_network" "test" {
+  name = "acc-member-addresses"
+
+  route {
+    target = "10.0.${length(zerotier_network.other.id)}.0/24"
+  }
+}
+
+resource "zerotier_member" "test" {
+  network_id     = zerotier_network.test.id
+  member_id      = "a1b2c3d4e5"
+  ip_assignments = [%q]
+}
+`, ip))
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-member-addresses"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+}
+
+resource "zerotier_member" "test" {
+  network_id     = zerotier_network.test.id
+  member_id      = "a1b2c3d4e5"
+  ip_assignments = ["10.0.0.1"]
+}
+`),
+			},
+			{
+				Config:      config("192.168.1.1"),
+				ExpectError: regexp.MustCompile(`ip_assignments: 192.168.1.1 is not inside any of the network's routes\s+\(10.0.16.0/24\)`),
+			},
+			{
+				Config: config("10.0.16.1"),
+				Check:  resource.TestCheckTypeSetElemAttr("zerotier_member.test", "ip_assignments.*", "10.0.16.1"),
+			},
+		},
+	})
+}
+
+func hclStringList(values []string) string {
+	quoted := []string{}
+	for _, value := range values {
+		quoted = append(quoted, strconv.Quote(value))
+	}
+
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
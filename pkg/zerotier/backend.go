@@ -0,0 +1,43 @@
+package zerotier
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+// errUnsupported is returned by backends for operations they cannot perform.
+var errUnsupported = errors.New("operation is not supported by the configured controller backend")
+
+// backend is the set of controller operations the resources and data sources
+// are built on. centralBackend is the ZeroTier Central implementation;
+// localBackend speaks to a self-hosted zerotier-one controller.
+//
+// Backends return nil for fields they have no notion of (a member's
+// description on a self-hosted controller, for example) and the conversions
+// into terraform leave those attributes alone.
+type backend interface {
+	GetNetworks(ctx context.Context) ([]*spec.Network, error)
+	GetNetwork(ctx context.Context, networkID string) (*spec.Network, error)
+	NewNetwork(ctx context.Context, name string, n *spec.Network) (*spec.Network, error)
+	UpdateNetwork(ctx context.Context, networkID string, n *spec.Network) (*spec.Network, error)
+	UpdateNetworkRules(ctx context.Context, networkID, source string) (string, error)
+	DeleteNetwork(ctx context.Context, networkID string) error
+
+	GetMembers(ctx context.Context, networkID string) ([]*spec.Member, error)
+	GetMember(ctx context.Context, networkID, memberID string) (*spec.Member, error)
+	CreateAuthorizedMember(ctx context.Context, networkID, memberID, name string) (*spec.Member, error)
+	UpdateMember(ctx context.Context, networkID, memberID string, m *spec.Member) (*spec.Member, error)
+	DeleteMember(ctx context.Context, networkID, memberID string) error
+
+	User(ctx context.Context) (*spec.User, error)
+	RandomToken(ctx context.Context) (string, error)
+	CreateAPIToken(ctx context.Context, userID, name, token string) error
+	DeleteAPIToken(ctx context.Context, userID, name string) error
+}
+
+var (
+	_ backend = (*centralBackend)(nil)
+	_ backend = (*localBackend)(nil)
+)
@@ -0,0 +1,230 @@
+package zerotier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/zerotier/go-ztcentral"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+// centralBackend talks to ZeroTier Central through the generated API client.
+//
+// go-ztcentral's Client sends everything through http.DefaultTransport and
+// discards the response on failure, which leaves no room for retries or
+// rate-limit handling; the calls we need are made here instead, over a
+// transport we control.
+type centralBackend struct {
+	client *spec.Client
+}
+
+// centralTransport authenticates requests to Central.
+type centralTransport struct {
+	token     string
+	userAgent string
+	next      http.RoundTripper
+}
+
+// statusError is returned by the backends when the controller responds with
+// anything but a 200. It wraps ztcentral.ErrStatus.
+type statusError struct {
+	StatusCode int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("Status code %v: %v", e.StatusCode, ztcentral.ErrStatus)
+}
+
+func (e *statusError) Unwrap() error {
+	return ztcentral.ErrStatus
+}
+
+// isNotFound reports whether err is the controller saying the object does not
+// exist, as opposed to refusing or failing to tell us.
+func isNotFound(err error) bool {
+	var serr *statusError
+	return errors.As(err, &serr) && serr.StatusCode == http.StatusNotFound
+}
+
+func newCentralBackend(url, token string, transport http.RoundTripper) (*centralBackend, error) {
+	client, err := spec.NewClient(url, spec.WithHTTPClient(&http.Client{
+		Transport: &centralTransport{
+			token:     token,
+			userAgent: fmt.Sprintf("terraform-provider-zerotier/%s", Version),
+			next:      transport,
+		},
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &centralBackend{client: client}, nil
+}
+
+func (t *centralTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", fmt.Sprintf("bearer %s", t.token))
+
+	return t.next.RoundTrip(req)
+}
+
+// decodeResponse checks the status of resp and decodes its body into i, if i is not
+// nil. resp is always closed.
+func decodeResponse(resp *http.Response, err error, i interface{}) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		io.Copy(io.Discard, resp.Body)
+		return &statusError{StatusCode: resp.StatusCode}
+	}
+
+	if i == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(i)
+}
+
+func (c *centralBackend) GetNetworks(ctx context.Context) ([]*spec.Network, error) {
+	var res []*spec.Network
+	resp, err := c.client.GetNetworkList(ctx)
+	return res, decodeResponse(resp, err, &res)
+}
+
+func (c *centralBackend) GetNetwork(ctx context.Context, networkID string) (*spec.Network, error) {
+	res := &spec.Network{}
+	resp, err := c.client.GetNetworkByID(ctx, networkID)
+	return res, decodeResponse(resp, err, res)
+}
+
+func (c *centralBackend) NewNetwork(ctx context.Context, name string, n *spec.Network) (*spec.Network, error) {
+	if n.Config != nil {
+		n.Config.Name = &name
+	} else {
+		n.Config = &spec.NetworkConfig{Name: &name}
+	}
+
+	content, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+
+	body := spec.NewNetworkJSONRequestBody{}
+	if err := json.Unmarshal(content, &body); err != nil {
+		return nil, err
+	}
+
+	res := &spec.Network{}
+	resp, err := c.client.NewNetwork(notIdempotent(ctx), body)
+	return res, decodeResponse(resp, err, res)
+}
+
+func (c *centralBackend) UpdateNetwork(ctx context.Context, networkID string, n *spec.Network) (*spec.Network, error) {
+	res := &spec.Network{}
+	resp, err := c.client.UpdateNetwork(ctx, networkID, spec.UpdateNetworkJSONRequestBody(*n))
+	return res, decodeResponse(resp, err, res)
+}
+
+func (c *centralBackend) UpdateNetworkRules(ctx context.Context, networkID, source string) (string, error) {
+	n, err := c.UpdateNetwork(ctx, networkID, &spec.Network{Id: &networkID, RulesSource: &source})
+	if err != nil {
+		return "", err
+	}
+
+	if n.RulesSource == nil {
+		return "", nil
+	}
+
+	return *n.RulesSource, nil
+}
+
+func (c *centralBackend) DeleteNetwork(ctx context.Context, networkID string) error {
+	resp, err := c.client.DeleteNetwork(ctx, networkID)
+	return decodeResponse(resp, err, nil)
+}
+
+func (c *centralBackend) GetMembers(ctx context.Context, networkID string) ([]*spec.Member, error) {
+	var res []*spec.Member
+	resp, err := c.client.GetNetworkMemberList(ctx, networkID)
+	return res, decodeResponse(resp, err, &res)
+}
+
+func (c *centralBackend) GetMember(ctx context.Context, networkID, memberID string) (*spec.Member, error) {
+	res := &spec.Member{}
+	resp, err := c.client.GetNetworkMember(ctx, networkID, memberID)
+	return res, decodeResponse(resp, err, res)
+}
+
+func (c *centralBackend) CreateAuthorizedMember(ctx context.Context, networkID, memberID, name string) (*spec.Member, error) {
+	return c.UpdateMember(ctx, networkID, memberID, &spec.Member{
+		NetworkId: &networkID,
+		NodeId:    &memberID,
+		Name:      &name,
+		Config: &spec.MemberConfig{
+			Authorized: boolPtr(true),
+		},
+	})
+}
+
+func (c *centralBackend) UpdateMember(ctx context.Context, networkID, memberID string, m *spec.Member) (*spec.Member, error) {
+	res := &spec.Member{}
+	resp, err := c.client.UpdateNetworkMember(ctx, networkID, memberID, spec.UpdateNetworkMemberJSONRequestBody(*m))
+	return res, decodeResponse(resp, err, res)
+}
+
+func (c *centralBackend) DeleteMember(ctx context.Context, networkID, memberID string) error {
+	resp, err := c.client.DeleteNetworkMember(ctx, networkID, memberID)
+	return decodeResponse(resp, err, nil)
+}
+
+func (c *centralBackend) User(ctx context.Context) (*spec.User, error) {
+	res := &spec.Status{}
+	resp, err := c.client.GetStatus(ctx)
+	if err := decodeResponse(resp, err, res); err != nil {
+		return nil, err
+	}
+
+	return res.User, nil
+}
+
+func (c *centralBackend) RandomToken(ctx context.Context) (string, error) {
+	res := &spec.RandomToken{}
+	resp, err := c.client.GetRandomToken(ctx)
+	if err := decodeResponse(resp, err, res); err != nil {
+		return "", err
+	}
+
+	if res.Token == nil {
+		return "", errors.New("central did not return a token")
+	}
+
+	return *res.Token, nil
+}
+
+func (c *centralBackend) CreateAPIToken(ctx context.Context, userID, name, token string) error {
+	if len(token) < 32 {
+		return errors.New("token must be a minimum of 32 characters")
+	}
+
+	resp, err := c.client.AddAPIToken(notIdempotent(ctx), userID, spec.AddAPITokenJSONRequestBody{
+		Token:     &token,
+		TokenName: &name,
+	})
+
+	return decodeResponse(resp, err, nil)
+}
+
+func (c *centralBackend) DeleteAPIToken(ctx context.Context, userID, name string) error {
+	resp, err := c.client.DeleteAPIToken(ctx, userID, name)
+	return decodeResponse(resp, err, nil)
+}
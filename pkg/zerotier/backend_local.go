@@ -0,0 +1,386 @@
+package zerotier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/rules"
+)
+
+const (
+	// defaultLocalControllerURL is the address of the zerotier-one service API.
+	defaultLocalControllerURL = "http://localhost:9993"
+	// defaultLocalControllerTokenFile is where zerotier-one keeps its API
+	// token on most linux installs.
+	defaultLocalControllerTokenFile = "/var/lib/zerotier-one/authtoken.secret"
+)
+
+// these are managed by the controller and are refused, or silently
+// overwritten, when posted back to it.
+var (
+	localNetworkReadOnly = []string{"id", "nwid", "objtype", "creationTime", "lastModified", "revision", "ssoConfig"}
+	localMemberReadOnly  = []string{
+		"id", "address", "nwid", "objtype", "creationTime", "identity", "revision",
+		"lastAuthorizedTime", "lastDeauthorizedTime", "vMajor", "vMinor", "vRev", "vProto",
+	}
+)
+
+// localBackend talks to the controller embedded in a self-hosted zerotier-one
+// node through its /controller JSON API.
+//
+// The local controller has no notion of descriptions or hidden members and
+// does not manage API tokens or SSO. It does not compile flow rules either;
+// that is done here.
+type localBackend struct {
+	url       string
+	token     string
+	userAgent string
+	client    *http.Client
+}
+
+// localNetwork is the controller's network object. Its configuration fields
+// share their names with central's NetworkConfig.
+type localNetwork struct {
+	spec.NetworkConfig
+	RulesSource *string `json:"rulesSource"`
+}
+
+// localMember is the controller's member object. Its configuration fields
+// share their names with central's MemberConfig.
+type localMember struct {
+	spec.MemberConfig
+	Address string  `json:"address"`
+	Nwid    string  `json:"nwid"`
+	Name    *string `json:"name"`
+}
+
+func newLocalBackend(url, token string, transport http.RoundTripper) *localBackend {
+	return &localBackend{
+		url:       strings.TrimSuffix(url, "/"),
+		token:     token,
+		userAgent: fmt.Sprintf("terraform-provider-zerotier/%s", Version),
+		client:    &http.Client{Transport: transport},
+	}
+}
+
+// isLocalBackend reports whether c speaks to a self-hosted controller, for
+// refusing what only Central offers when planning.
+func isLocalBackend(c backend) bool {
+	_, ok := underlyingBackend(c).(*localBackend)
+	return ok
+}
+
+// readLocalToken reads the zerotier-one API token from filename.
+func readLocalToken(filename string) (string, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("could not read controller token: %w", err)
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+func (l *localBackend) do(ctx context.Context, method, path string, body, res interface{}) error {
+	var buf bytes.Buffer
+
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, l.url+path, &buf)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-ZT1-Auth", l.token)
+	req.Header.Set("User-Agent", l.userAgent)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := l.client.Do(req)
+	return decodeResponse(resp, err, res)
+}
+
+func (l *localBackend) GetNetworks(ctx context.Context) ([]*spec.Network, error) {
+	ids := []string{}
+	if err := l.do(ctx, "GET", "/controller/network", nil, &ids); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(ids)
+
+	res := []*spec.Network{}
+	for _, id := range ids {
+		n, err := l.GetNetwork(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, n)
+	}
+
+	return res, nil
+}
+
+func (l *localBackend) GetNetwork(ctx context.Context, networkID string) (*spec.Network, error) {
+	n := &localNetwork{}
+	if err := l.do(ctx, "GET", "/controller/network/"+networkID, nil, n); err != nil {
+		return nil, err
+	}
+
+	return n.toSpec(), nil
+}
+
+func (l *localBackend) NewNetwork(ctx context.Context, name string, n *spec.Network) (*spec.Network, error) {
+	if localSSOEnabled(n) {
+		return nil, errUnsupported
+	}
+
+	status := struct {
+		Address string `json:"address"`
+	}{}
+
+	if err := l.do(ctx, "GET", "/status", nil, &status); err != nil {
+		return nil, err
+	}
+
+	body := localNetworkBody(n)
+	body["name"] = name
+
+	// the trailing underscores ask the controller to pick a free network ID
+	// for this node.
+	res := &localNetwork{}
+	if err := l.do(notIdempotent(ctx), "POST", fmt.Sprintf("/controller/network/%s______", status.Address), body, res); err != nil {
+		return nil, err
+	}
+
+	return res.toSpec(), nil
+}
+
+func (l *localBackend) UpdateNetwork(ctx context.Context, networkID string, n *spec.Network) (*spec.Network, error) {
+	if localSSOEnabled(n) {
+		return nil, errUnsupported
+	}
+
+	res := &localNetwork{}
+	if err := l.do(ctx, "POST", "/controller/network/"+networkID, localNetworkBody(n), res); err != nil {
+		return nil, err
+	}
+
+	return res.toSpec(), nil
+}
+
+// UpdateNetworkRules compiles source itself, as the controller only runs the
+// compiled rules and keeps the source for reference.
+func (l *localBackend) UpdateNetworkRules(ctx context.Context, networkID, source string) (string, error) {
+	program, err := rules.Compile(source)
+	if err != nil {
+		return "", err
+	}
+
+	capabilities := []map[string]interface{}{}
+	for _, c := range program.Capabilities {
+		capabilities = append(capabilities, map[string]interface{}{"id": c.ID, "rules": c.Rules})
+	}
+	sort.Slice(capabilities, func(i, j int) bool {
+		return capabilities[i]["id"].(uint32) < capabilities[j]["id"].(uint32)
+	})
+
+	tags := []map[string]interface{}{}
+	for _, t := range program.Tags {
+		tags = append(tags, map[string]interface{}{"id": t.ID, "default": t.Default})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i]["id"].(uint32) < tags[j]["id"].(uint32)
+	})
+
+	body := map[string]interface{}{
+		"rulesSource":  source,
+		"rules":        program.Rules,
+		"capabilities": capabilities,
+		"tags":         tags,
+	}
+
+	res := &localNetwork{}
+	if err := l.do(ctx, "POST", "/controller/network/"+networkID, body, res); err != nil {
+		return "", err
+	}
+
+	if res.RulesSource == nil {
+		return "", nil
+	}
+
+	return *res.RulesSource, nil
+}
+
+func (l *localBackend) DeleteNetwork(ctx context.Context, networkID string) error {
+	return l.do(ctx, "DELETE", "/controller/network/"+networkID, nil, nil)
+}
+
+func (l *localBackend) GetMembers(ctx context.Context, networkID string) ([]*spec.Member, error) {
+	// the listing is a map of member ID to revision.
+	revisions := map[string]interface{}{}
+	if err := l.do(ctx, "GET", fmt.Sprintf("/controller/network/%s/member", networkID), nil, &revisions); err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for id := range revisions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	res := []*spec.Member{}
+	for _, id := range ids {
+		m, err := l.GetMember(ctx, networkID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, m)
+	}
+
+	return res, nil
+}
+
+func (l *localBackend) GetMember(ctx context.Context, networkID, memberID string) (*spec.Member, error) {
+	m := &localMember{}
+	if err := l.do(ctx, "GET", fmt.Sprintf("/controller/network/%s/member/%s", networkID, memberID), nil, m); err != nil {
+		return nil, err
+	}
+
+	return m.toSpec(), nil
+}
+
+func (l *localBackend) CreateAuthorizedMember(ctx context.Context, networkID, memberID, name string) (*spec.Member, error) {
+	return l.UpdateMember(ctx, networkID, memberID, &spec.Member{
+		Name:   &name,
+		Config: &spec.MemberConfig{Authorized: boolPtr(true)},
+	})
+}
+
+func (l *localBackend) UpdateMember(ctx context.Context, networkID, memberID string, m *spec.Member) (*spec.Member, error) {
+	// hiding members is only offered by Central.
+	if ptrBool(m.Hidden) {
+		return nil, errUnsupported
+	}
+
+	body := map[string]interface{}{}
+	if m.Config != nil {
+		body = toLocalBody(m.Config, localMemberReadOnly)
+	}
+
+	if m.Name != nil {
+		body["name"] = *m.Name
+	}
+
+	res := &localMember{}
+	if err := l.do(ctx, "POST", fmt.Sprintf("/controller/network/%s/member/%s", networkID, memberID), body, res); err != nil {
+		return nil, err
+	}
+
+	return res.toSpec(), nil
+}
+
+func (l *localBackend) DeleteMember(ctx context.Context, networkID, memberID string) error {
+	return l.do(ctx, "DELETE", fmt.Sprintf("/controller/network/%s/member/%s", networkID, memberID), nil, nil)
+}
+
+func (l *localBackend) User(ctx context.Context) (*spec.User, error) {
+	return nil, errUnsupported
+}
+
+func (l *localBackend) RandomToken(ctx context.Context) (string, error) {
+	return "", errUnsupported
+}
+
+func (l *localBackend) CreateAPIToken(ctx context.Context, userID, name, token string) error {
+	return errUnsupported
+}
+
+func (l *localBackend) DeleteAPIToken(ctx context.Context, userID, name string) error {
+	return errUnsupported
+}
+
+func (n *localNetwork) toSpec() *spec.Network {
+	config := n.NetworkConfig
+
+	return &spec.Network{
+		Id:          config.Id,
+		RulesSource: n.RulesSource,
+		Config:      &config,
+	}
+}
+
+func (m *localMember) toSpec() *spec.Member {
+	config := m.MemberConfig
+
+	return &spec.Member{
+		Id:        stringPtr(m.Nwid + "-" + m.Address),
+		NetworkId: stringPtr(m.Nwid),
+		NodeId:    stringPtr(m.Address),
+		Name:      m.Name,
+		Config:    &config,
+	}
+}
+
+// localSSOEnabled reports whether n enables SSO, which is only offered by
+// Central.
+func localSSOEnabled(n *spec.Network) bool {
+	return n.Config != nil && n.Config.SsoConfig != nil && ptrBool(n.Config.SsoConfig.Enabled)
+}
+
+func localNetworkBody(n *spec.Network) map[string]interface{} {
+	body := map[string]interface{}{}
+	if n.Config != nil {
+		body = toLocalBody(n.Config, localNetworkReadOnly)
+	}
+
+	if n.RulesSource != nil {
+		body["rulesSource"] = *n.RulesSource
+	}
+
+	return body
+}
+
+// toLocalBody converts one of central's configuration objects into a request
+// body for the local controller, dropping unset and read-only fields.
+func toLocalBody(i interface{}, readOnly []string) map[string]interface{} {
+	body := map[string]interface{}{}
+
+	content, err := json.Marshal(i)
+	if err != nil {
+		return body
+	}
+
+	if err := json.Unmarshal(content, &body); err != nil {
+		return body
+	}
+
+	dropNulls(body)
+
+	for _, key := range readOnly {
+		delete(body, key)
+	}
+
+	return body
+}
+
+func dropNulls(m map[string]interface{}) {
+	for key, value := range m {
+		switch value := value.(type) {
+		case nil:
+			delete(m, key)
+		case map[string]interface{}:
+			dropNulls(value)
+		}
+	}
+}
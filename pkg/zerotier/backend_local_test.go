@@ -0,0 +1,305 @@
+package zerotier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecontroller"
+)
+
+func testLocalBackend(t *testing.T) (*fakecontroller.Server, *localBackend) {
+	srv := fakecontroller.New(testAccToken)
+	t.Cleanup(srv.Close)
+
+	return srv, newLocalBackend(srv.URL(), srv.Token, http.DefaultTransport)
+}
+
+func TestLocalBackend_Networks(t *testing.T) {
+	srv, c := testLocalBackend(t)
+	ctx := context.Background()
+
+	pools := []spec.IPRange{{IpRangeStart: stringPtr("10.1.0.1"), IpRangeEnd: stringPtr("10.1.0.254")}}
+	routes := []spec.Route{{Target: stringPtr("10.1.0.0/24")}}
+
+	n, err := c.NewNetwork(ctx, "local", &spec.Network{
+		Description: stringPtr("not stored"),
+		Config: &spec.NetworkConfig{
+			Private:           boolPtr(false),
+			MulticastLimit:    intPtr(64),
+			IpAssignmentPools: &pools,
+			Routes:            &routes,
+			V4AssignMode:      &spec.IPV4AssignMode{Zt: boolPtr(true)},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Regexp(t, "^"+fakecontroller.Address+"[0-9a-f]{6}$", *n.Id)
+	assert.Equal(t, "local", *n.Config.Name)
+	assert.False(t, *n.Config.Private)
+	assert.Equal(t, 64, *n.Config.MulticastLimit)
+	assert.Equal(t, "10.1.0.1", *(*n.Config.IpAssignmentPools)[0].IpRangeStart)
+	assert.Equal(t, "10.1.0.0/24", *(*n.Config.Routes)[0].Target)
+	assert.True(t, *n.Config.V4AssignMode.Zt)
+	assert.NotNil(t, n.Config.CreationTime)
+	assert.Nil(t, n.Description)
+
+	rules, err := c.UpdateNetworkRules(ctx, *n.Id, "drop;")
+	assert.NoError(t, err)
+	assert.Equal(t, "drop;", rules)
+	assert.Equal(t, "drop;", srv.Network(*n.Id)["rulesSource"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "ACTION_DROP"}}, srv.Network(*n.Id)["rules"])
+
+	source := "tag role id 5 default 1; cap ssh id 7 accept dport 22; ; accept;"
+	rules, err = c.UpdateNetworkRules(ctx, *n.Id, source)
+	assert.NoError(t, err)
+	assert.Equal(t, source, rules)
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": float64(5), "default": float64(1)}}, srv.Network(*n.Id)["tags"])
+	assert.Len(t, srv.Network(*n.Id)["capabilities"], 1)
+	assert.Equal(t, float64(7), srv.Network(*n.Id)["capabilities"].([]interface{})[0].(map[string]interface{})["id"])
+
+	_, err = c.UpdateNetworkRules(ctx, *n.Id, "dorp;")
+	assert.ErrorContains(t, err, `line 1, column 1: unknown action "dorp"`)
+
+	rules, err = c.UpdateNetworkRules(ctx, *n.Id, "drop;")
+	assert.NoError(t, err)
+	assert.Equal(t, "drop;", rules)
+
+	updated, err := c.UpdateNetwork(ctx, *n.Id, &spec.Network{Config: &spec.NetworkConfig{Name: stringPtr("renamed")}})
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed", *updated.Config.Name)
+	assert.False(t, *updated.Config.Private, "unset fields should be left alone")
+	assert.Equal(t, "drop;", *updated.RulesSource)
+
+	networks, err := c.GetNetworks(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, networks, 1)
+	assert.Equal(t, *n.Id, *networks[0].Id)
+
+	assert.NoError(t, c.DeleteNetwork(ctx, *n.Id))
+
+	_, err = c.GetNetwork(ctx, *n.Id)
+	assert.True(t, errors.Is(err, ztcentral.ErrStatus))
+}
+
+func TestLocalBackend_Members(t *testing.T) {
+	srv, c := testLocalBackend(t)
+	ctx := context.Background()
+
+	n, err := c.NewNetwork(ctx, "local", &spec.Network{})
+	assert.NoError(t, err)
+
+	m, err := c.CreateAuthorizedMember(ctx, *n.Id, "a1b2c3d4e5", "alice")
+	assert.NoError(t, err)
+	assert.True(t, *m.Config.Authorized)
+	assert.Equal(t, "a1b2c3d4e5", *m.NodeId)
+	assert.Equal(t, *n.Id, *m.NetworkId)
+
+	ips := []string{"10.1.0.5"}
+	m, err = c.UpdateMember(ctx, *n.Id, "a1b2c3d4e5", &spec.Member{
+		Description: stringPtr("not stored"),
+		Hidden:      boolPtr(false),
+		Config: &spec.MemberConfig{
+			Authorized:    boolPtr(false),
+			IpAssignments: &ips,
+			Revision:      intPtr(1000),
+		},
+	})
+	assert.NoError(t, err)
+	assert.False(t, *m.Config.Authorized)
+	assert.Equal(t, ips, *m.Config.IpAssignments)
+	assert.Equal(t, "alice", *m.Name)
+	assert.Nil(t, m.Description)
+	assert.Nil(t, m.Hidden)
+	assert.Equal(t, float64(2), srv.Member(*n.Id, "a1b2c3d4e5")["revision"], "read-only fields should not be posted")
+
+	_, err = c.CreateAuthorizedMember(ctx, *n.Id, "b1b2c3d4e5", "bob")
+	assert.NoError(t, err)
+
+	members, err := c.GetMembers(ctx, *n.Id)
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+	assert.Equal(t, "a1b2c3d4e5", *members[0].NodeId)
+	assert.Equal(t, "b1b2c3d4e5", *members[1].NodeId)
+
+	assert.NoError(t, c.DeleteMember(ctx, *n.Id, "a1b2c3d4e5"))
+	assert.Nil(t, srv.Member(*n.Id, "a1b2c3d4e5"))
+}
+
+func TestLocalBackend_Unsupported(t *testing.T) {
+	_, c := testLocalBackend(t)
+	ctx := context.Background()
+
+	_, err := c.User(ctx)
+	assert.Equal(t, errUnsupported, err)
+	_, err = c.RandomToken(ctx)
+	assert.Equal(t, errUnsupported, err)
+	assert.Equal(t, errUnsupported, c.CreateAPIToken(ctx, "user", "name", "token"))
+	assert.Equal(t, errUnsupported, c.DeleteAPIToken(ctx, "user", "name"))
+
+	sso := &spec.Network{Config: &spec.NetworkConfig{
+		SsoConfig: &spec.NetworkSSOConfig{Enabled: boolPtr(true)},
+	}}
+
+	_, err = c.NewNetwork(ctx, "sso", sso)
+	assert.Equal(t, errUnsupported, err)
+	networks, err := c.GetNetworks(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, networks)
+
+	_, err = c.UpdateNetwork(ctx, "0123456789abcdef", sso)
+	assert.Equal(t, errUnsupported, err)
+
+	_, err = c.UpdateMember(ctx, "0123456789abcdef", "a1b2c3d4e5", &spec.Member{Hidden: boolPtr(true)})
+	assert.Equal(t, errUnsupported, err)
+}
+
+func TestLocalBackend_BadToken(t *testing.T) {
+	srv, _ := testLocalBackend(t)
+
+	_, err := newLocalBackend(srv.URL(), "wrong", http.DefaultTransport).GetNetworks(context.Background())
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestAccLocalController_basic(t *testing.T) {
+	srv := fakecontroller.New(testAccToken)
+	t.Cleanup(srv.Close)
+
+	config := fmt.Sprintf(`
+provider "zerotier" {
+  local_controller {
+    url   = %q
+    token = %q
+  }
+}
+
+resource "zerotier_network" "test" {
+  name       = "self-hosted"
+  flow_rules = "drop;"
+
+  assignment_pool {
+    start = "10.2.0.1"
+    end   = "10.2.0.254"
+  }
+
+  route {
+    target = "10.2.0.0/24"
+  }
+}
+
+resource "zerotier_member" "test" {
+  network_id     = zerotier_network.test.id
+  member_id      = "a1b2c3d4e5"
+  name           = "alice"
+  description    = "only kept in state"
+  ip_assignments = ["10.2.0.10"]
+}
+`, srv.URL(), srv.Token)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestMatchResourceAttr("zerotier_network.test", "id", regexp.MustCompile("^"+fakecontroller.Address)),
+					resource.TestCheckResourceAttr("zerotier_network.test", "name", "self-hosted"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "flow_rules", "drop;"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "route.#", "1"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "authorized", "true"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "description", "only kept in state"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "ip_assignments.#", "1"),
+				),
+			},
+			{
+				// nothing the controller drops should show up as a diff.
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccLocalController_sso(t *testing.T) {
+	srv := fakecontroller.New(testAccToken)
+	t.Cleanup(srv.Close)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "zerotier" {
+  local_controller {
+    url   = %q
+    token = %q
+  }
+}
+
+resource "zerotier_network" "test" {
+  name = "self-hosted"
+
+  sso {
+    issuer    = "https://issuer.example.com"
+    client_id = "zerotier"
+  }
+}
+`, srv.URL(), srv.Token),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`sso: SSO is only offered by ZeroTier Central, not a local controller`),
+			},
+		},
+	})
+}
+
+func TestAccLocalController_hideOnDestroy(t *testing.T) {
+	srv := fakecontroller.New(testAccToken)
+	t.Cleanup(srv.Close)
+
+	config := func(onDestroy string) string {
+		return fmt.Sprintf(`
+provider "zerotier" {
+  local_controller {
+    url   = %q
+    token = %q
+  }
+}
+
+resource "zerotier_network" "test" {
+  name = "self-hosted"
+}
+
+resource "zerotier_member" "test" {
+  network_id = zerotier_network.test.id
+  member_id  = "a1b2c3d4e5"
+  on_destroy = %q
+}
+`, srv.URL(), srv.Token, onDestroy)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      config("hide"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`on_destroy: hide is only offered by ZeroTier Central, not a local\s+controller`),
+			},
+			{
+				Config: config("deauthorize"),
+			},
+			{
+				// nor can an existing member be switched to it.
+				Config:      config("hide"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`on_destroy: hide is only offered by ZeroTier Central, not a local\s+controller`),
+			},
+		},
+	})
+}
@@ -2,6 +2,7 @@ package zerotier
 
 import (
 	"errors"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
@@ -25,19 +26,32 @@ func stringPtr(s string) *string {
 	return &s
 }
 
+func ptrString(p *string) string {
+	if p != nil {
+		return *p
+	}
+
+	return ""
+}
+
 func intPtr(i int) *int {
 	return &i
 }
 
-func getMemberIDs(d *schema.ResourceData) (string, string) {
-	ztNetworkID := d.Get("network_id").(string)
-	memberID := d.Get("member_id").(string)
+func ptrInt(p *int) int {
+	if p != nil {
+		return *p
+	}
 
-	if ztNetworkID == "" && memberID == "" {
-		parts := strings.Split(d.Id(), "-")
-		ztNetworkID, memberID = parts[0], parts[1]
+	return 0
+}
+
+func ptrInt64(p *int64) int64 {
+	if p != nil {
+		return *p
 	}
-	return ztNetworkID, memberID
+
+	return 0
 }
 
 func fetchStringList(d *schema.ResourceData, attr string) *[]string {
@@ -197,68 +211,129 @@ func mktfRanges(ranges *[]spec.IPRange) []map[string]interface{} {
 	return ret
 }
 
-func dnsset(m interface{}) int {
+// maxDNSServers is how many nameservers a ZeroTier network can push to its
+// members.
+const maxDNSServers = 4
 
+// dnsHash hashes a dns block by its domain and its servers, in order.
+func dnsHash(m interface{}) int {
 	dns := m.(map[string]interface{})
 
-	// this weird algorithm was written after midnight
-	ret := int64(0)
-
-	if obj, ok := dns["servers"]; ok && obj != nil {
-		for _, s := range dns["servers"].([]string) {
-			for _, c := range s {
-				ret += int64(c)
-			}
-			ret += 255 // meh
+	servers := []string{}
+	if s, ok := dns["servers"].([]interface{}); ok {
+		for _, server := range s {
+			servers = append(servers, server.(string))
 		}
 	}
 
-	if obj, ok := dns["domain"]; ok && obj != nil {
-		for _, c := range dns["domain"].(string) {
-			ret += int64(c)
-		}
-	}
+	domain, _ := dns["domain"].(string)
 
-	return int(ret >> 4) // super hella broken probably
+	return schema.HashString(domain + "\n" + strings.Join(servers, "\n"))
 }
 
 func mktfDNS(dns *spec.DNS) *schema.Set {
-	ret := map[string]interface{}{}
+	ret := schema.NewSet(dnsHash, []interface{}{})
 
-	if dns.Domain != nil {
-		ret["domain"] = *dns.Domain
+	// central reports a network without DNS as an empty domain and no
+	// servers, which is no dns block at all.
+	if dns == nil || (ptrString(dns.Domain) == "" && (dns.Servers == nil || len(*dns.Servers) == 0)) {
+		return ret
 	}
 
+	servers := []interface{}{}
 	if dns.Servers != nil {
-		ret["servers"] = *dns.Servers
+		for _, server := range *dns.Servers {
+			servers = append(servers, server)
+		}
 	}
 
-	return schema.NewSet(dnsset, []interface{}{ret})
+	ret.Add(map[string]interface{}{
+		"domain":  ptrString(dns.Domain),
+		"servers": servers,
+	})
+
+	return ret
 }
 
+// mkDNS converts the dns block. Without it the DNS settings are cleared.
 func mkDNS(settings interface{}) (interface{}, diag.Diagnostics) {
-	m := settings.(*schema.Set)
+	ret := &spec.DNS{Domain: stringPtr(""), Servers: &[]string{}}
 
-	ret := &spec.DNS{}
-
-	for _, set := range m.List() {
+	for _, set := range settings.(*schema.Set).List() {
 		tmp := set.(map[string]interface{})
 
-		if domain, ok := tmp["domain"].(string); ok {
-			ret.Domain = &domain
+		ret.Domain = stringPtr(tmp["domain"].(string))
+
+		servers := []string{}
+		for _, server := range tmp["servers"].([]interface{}) {
+			servers = append(servers, server.(string))
 		}
+		ret.Servers = &servers
+	}
 
-		if s, ok := tmp["servers"]; ok {
-			servers := []string{}
-			for _, server := range s.([]interface{}) {
-				servers = append(servers, server.(string))
-			}
+	return ret, nil
+}
+
+func mktfSSO(sso *spec.NetworkSSOConfig) []interface{} {
+	// a network that never had SSO enabled has nothing to show.
+	if !ptrBool(sso.Enabled) && ptrString(sso.Issuer) == "" && ptrString(sso.ClientId) == "" {
+		return []interface{}{}
+	}
+
+	allowed := []string{}
+	if sso.AllowList != nil {
+		allowed = *sso.AllowList
+	}
+
+	mode := ptrString(sso.Mode)
+	if mode == "" {
+		mode = "default"
+	}
 
-			ret.Servers = &servers
+	return []interface{}{map[string]interface{}{
+		"enabled":                ptrBool(sso.Enabled),
+		"mode":                   mode,
+		"issuer":                 ptrString(sso.Issuer),
+		"client_id":              ptrString(sso.ClientId),
+		"authorization_endpoint": ptrString(sso.AuthorizationEndpoint),
+		"allowed_members":        allowed,
+	}}
+}
+
+// mkSSO converts the sso block. Without it SSO is disabled and its settings
+// are cleared, so that they don't come back on the next read.
+func mkSSO(settings interface{}) *spec.NetworkSSOConfig {
+	ret := &spec.NetworkSSOConfig{
+		Enabled:               boolPtr(false),
+		Mode:                  stringPtr("default"),
+		Issuer:                stringPtr(""),
+		ClientId:              stringPtr(""),
+		AuthorizationEndpoint: stringPtr(""),
+		AllowList:             &[]string{},
+	}
+
+	for _, s := range settings.([]interface{}) {
+		if s == nil {
+			continue
+		}
+
+		tmp := s.(map[string]interface{})
+
+		ret.Enabled = boolPtr(tmp["enabled"].(bool))
+		ret.Mode = stringPtr(tmp["mode"].(string))
+		ret.Issuer = stringPtr(tmp["issuer"].(string))
+		ret.ClientId = stringPtr(tmp["client_id"].(string))
+		ret.AuthorizationEndpoint = stringPtr(tmp["authorization_endpoint"].(string))
+
+		allowed := []string{}
+		for _, member := range tmp["allowed_members"].(*schema.Set).List() {
+			allowed = append(allowed, member.(string))
 		}
+		sort.Strings(allowed)
+		ret.AllowList = &allowed
 	}
 
-	return ret, nil
+	return ret
 }
 
 func ipv6set(m interface{}) int {
@@ -2,6 +2,7 @@ package zerotier
 
 import (
 	// "log"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -69,3 +70,141 @@ func TestZeroTier_ToMember(t *testing.T) {
 	assert.Equal(t, *expected.Config.ActiveBridge, *out.Config.ActiveBridge)
 	assert.Equal(t, *expected.Config.NoAutoAssignIps, *out.Config.NoAutoAssignIps)
 }
+
+func TestZeroTier_SSO(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceNetwork().Schema, map[string]interface{}{
+		"sso": []interface{}{map[string]interface{}{
+			"mode":            "email",
+			"issuer":          "https://issuer.example.com",
+			"client_id":       "terraform",
+			"allowed_members": []interface{}{"bob@example.com", "alice@example.com"},
+		}},
+	})
+
+	sso := mkSSO(d.Get("sso"))
+	assert.Equal(t, &spec.NetworkSSOConfig{
+		Enabled:               boolPtr(true),
+		Mode:                  stringPtr("email"),
+		Issuer:                stringPtr("https://issuer.example.com"),
+		ClientId:              stringPtr("terraform"),
+		AuthorizationEndpoint: stringPtr(""),
+		AllowList:             &[]string{"alice@example.com", "bob@example.com"},
+	}, sso)
+
+	tf := mktfSSO(sso)
+	assert.Len(t, tf, 1)
+	assert.Equal(t, true, tf[0].(map[string]interface{})["enabled"])
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, tf[0].(map[string]interface{})["allowed_members"])
+
+	// without the block, SSO is disabled and cleared, and reads back as no
+	// block at all.
+	d = schema.TestResourceDataRaw(t, resourceNetwork().Schema, map[string]interface{}{})
+
+	sso = mkSSO(d.Get("sso"))
+	assert.Equal(t, &spec.NetworkSSOConfig{
+		Enabled:               boolPtr(false),
+		Mode:                  stringPtr("default"),
+		Issuer:                stringPtr(""),
+		ClientId:              stringPtr(""),
+		AuthorizationEndpoint: stringPtr(""),
+		AllowList:             &[]string{},
+	}, sso)
+	assert.Empty(t, mktfSSO(sso))
+	assert.Empty(t, mktfSSO(&spec.NetworkSSOConfig{Enabled: boolPtr(false), Mode: stringPtr("default")}))
+}
+
+func TestZeroTier_NetworkRoundTrip(t *testing.T) {
+	raw := map[string]interface{}{
+		"id":               "0123456789abcdef",
+		"name":             "round-trip",
+		"description":      "there and back again",
+		"enable_broadcast": false,
+		"multicast_limit":  64,
+		"mtu":              1400,
+		"private":          false,
+		"flow_rules":       "drop;",
+		"route": []interface{}{
+			map[string]interface{}{"target": "10.0.0.0/24"},
+			map[string]interface{}{"target": "10.1.0.0/24", "via": "10.0.0.1"},
+		},
+		"assignment_pool": []interface{}{
+			map[string]interface{}{"start": "10.0.0.10", "end": "10.0.0.20"},
+		},
+		"assign_ipv4": []interface{}{
+			map[string]interface{}{"zerotier": false},
+		},
+		"assign_ipv6": []interface{}{
+			map[string]interface{}{"zerotier": true, "sixplane": true, "rfc4193": false},
+		},
+		"dns": []interface{}{
+			map[string]interface{}{"domain": "example.com", "servers": []interface{}{"10.0.0.2"}},
+		},
+		"sso": []interface{}{
+			map[string]interface{}{
+				"enabled":                false,
+				"mode":                   "group",
+				"issuer":                 "https://issuer.example.com",
+				"client_id":              "terraform",
+				"authorization_endpoint": "https://issuer.example.com/authorize",
+				"allowed_members":        []interface{}{"admins"},
+			},
+		},
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceNetwork().Schema, raw)
+	d.SetId("0123456789abcdef")
+
+	n, diags := toNetwork(d)
+	assert.False(t, diags.HasError())
+	assert.Equal(t, 1400, *n.Config.Mtu)
+	assert.Equal(t, 64, *n.Config.MulticastLimit)
+
+	// what Central fills in.
+	creationTime := int64(1234)
+	n.Config.CreationTime = &creationTime
+
+	out := schema.TestResourceDataRaw(t, resourceNetwork().Schema, map[string]interface{}{})
+	assert.False(t, networkToTerraform(out, n).HasError())
+
+	// compare the flattened attributes, as sets don't compare with Equal.
+	expected, actual := map[string]string{}, map[string]string{}
+	for key, value := range d.State().Attributes {
+		if _, ok := raw[strings.SplitN(key, ".", 2)[0]]; ok {
+			expected[key] = value
+		}
+	}
+	for key, value := range out.State().Attributes {
+		if _, ok := raw[strings.SplitN(key, ".", 2)[0]]; ok {
+			actual[key] = value
+		}
+	}
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, 1234, out.Get("creation_time"))
+}
+
+func TestZeroTier_DNS(t *testing.T) {
+	dns := func(domain string, servers ...interface{}) map[string]interface{} {
+		return map[string]interface{}{"domain": domain, "servers": servers}
+	}
+
+	// the hash is stable, and tells apart what the old character sum did not.
+	assert.Equal(t, dnsHash(dns("acc.test", "10.0.0.2")), dnsHash(dns("acc.test", "10.0.0.2")))
+	assert.NotEqual(t, dnsHash(dns("acc.test", "10.0.0.2")), dnsHash(dns("cca.test", "10.0.0.2")))
+	assert.NotEqual(t, dnsHash(dns("acc.test", "10.0.0.2", "10.0.0.3")), dnsHash(dns("acc.test", "10.0.0.3", "10.0.0.2")))
+	assert.NotEqual(t, dnsHash(dns("acc.test", "10.0.0.2", "10.0.0.3")), dnsHash(dns("acc.test", "10.0.0.210.0.0.3")))
+
+	assert.Equal(t, 0, mktfDNS(nil).Len())
+	assert.Equal(t, 0, mktfDNS(&spec.DNS{Domain: stringPtr("")}).Len())
+	assert.Equal(t, 0, mktfDNS(&spec.DNS{Domain: stringPtr(""), Servers: &[]string{}}).Len())
+
+	set := mktfDNS(&spec.DNS{Domain: stringPtr("acc.test"), Servers: &[]string{"10.0.0.2"}})
+	assert.Equal(t, []interface{}{dns("acc.test", "10.0.0.2")}, set.List())
+
+	d, diags := mkDNS(set)
+	assert.False(t, diags.HasError())
+	assert.Equal(t, &spec.DNS{Domain: stringPtr("acc.test"), Servers: &[]string{"10.0.0.2"}}, d)
+
+	d, diags = mkDNS(schema.NewSet(dnsHash, nil))
+	assert.False(t, diags.HasError())
+	assert.Equal(t, &spec.DNS{Domain: stringPtr(""), Servers: &[]string{}}, d)
+}
@@ -0,0 +1,126 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+func dataSourceMember() *schema.Resource {
+	return &schema.Resource{
+		Description: "Data source for a ZeroTier member, allowing you to find a member of a network by ID or by name.",
+		ReadContext: dataSourceMemberRead,
+		Schema:      dataSourceMemberSchema(),
+	}
+}
+
+// dataSourceMemberSchema is buildMemberSchema(false) with everything
+// computed but the network and one of the member ID or name to look up.
+func dataSourceMemberSchema() map[string]*schema.Schema {
+	s := computedSchema(buildMemberSchema(false))
+
+	s["network_id"].Computed = false
+	s["network_id"].Required = true
+	s["network_id"].Description = "ID of the network to find the member in."
+
+	for _, key := range []string{"member_id", "name"} {
+		s[key].Optional = true
+		s[key].ExactlyOneOf = []string{"member_id", "name"}
+	}
+	s["member_id"].Description = "ID of the member to find."
+	s["name"].Description = "Name of the member to find. Exactly one member of the network may have it."
+
+	s["online_threshold"] = onlineThresholdSchema()
+
+	return s
+}
+
+func dataSourceMemberRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	c := m.(backend)
+
+	nwid := d.Get("network_id").(string)
+
+	var (
+		member *spec.Member
+		diags  diag.Diagnostics
+	)
+
+	if memberID := d.Get("member_id").(string); memberID != "" {
+		member, diags = findMemberByID(ctx, c, nwid, memberID)
+	} else {
+		member, diags = findMemberByName(ctx, c, nwid, d.Get("name").(string))
+	}
+
+	if diags.HasError() {
+		return diags
+	}
+
+	return memberToTerraform(d, member)
+}
+
+func findMemberByID(ctx context.Context, c backend, nwid, memberID string) (*spec.Member, diag.Diagnostics) {
+	member, err := c.GetMember(ctx, nwid, memberID)
+	if isNotFound(err) {
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "ZeroTier Member not found",
+			Detail:   fmt.Sprintf("Network %s has no member %s.", nwid, memberID),
+		}}
+	}
+
+	if err != nil {
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Unable to read ZeroTier Member",
+			Detail:   fmt.Sprintf("GetMember returned error: %v", err),
+		}}
+	}
+
+	return member, nil
+}
+
+func findMemberByName(ctx context.Context, c backend, nwid, name string) (*spec.Member, diag.Diagnostics) {
+	members, err := c.GetMembers(ctx, nwid)
+	if err != nil {
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Unable to read ZeroTier Members",
+			Detail:   fmt.Sprintf("GetMembers returned error: %v", err),
+		}}
+	}
+
+	found := []*spec.Member{}
+	for _, member := range members {
+		if ptrString(member.Name) == name {
+			found = append(found, member)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "ZeroTier Member not found",
+			Detail:   fmt.Sprintf("Network %s has no member named %q.", nwid, name),
+		}}
+	case 1:
+		return found[0], nil
+	default:
+		ids := []string{}
+		for _, member := range found {
+			ids = append(ids, *member.NodeId)
+		}
+		sort.Strings(ids)
+
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Multiple ZeroTier Members found",
+			Detail:   fmt.Sprintf("Network %s has %d members named %q (%s). Look the member up by member_id instead.", nwid, len(found), name, strings.Join(ids, ", ")),
+		}}
+	}
+}
@@ -0,0 +1,115 @@
+package zerotier
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceMember_basic(t *testing.T) {
+	srv := testAccCentral(t)
+
+	config := func(lookup string) string {
+		return testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_network" "test" {
+  name = "bobs_garage"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+}
+
+resource "zerotier_member" "car" {
+  network_id     = zerotier_network.test.id
+  member_id      = "b0b5ca7000"
+  name           = "bobs_car"
+  description    = "bobs shiny car"
+  ip_assignments = ["10.0.0.5"]
+}
+
+resource "zerotier_member" "bike" {
+  network_id = zerotier_network.test.id
+  member_id  = "b0b5b1ce00"
+  name       = "bobs_ride"
+}
+
+resource "zerotier_member" "scooter" {
+  network_id = zerotier_network.test.id
+  member_id  = "b0b55c0000"
+  name       = "bobs_ride"
+}
+
+data "zerotier_member" "test" {
+  depends_on = [zerotier_member.car, zerotier_member.bike, zerotier_member.scooter]
+  network_id = zerotier_network.test.id
+  %s
+}
+`, lookup))
+	}
+
+	check := resource.ComposeTestCheckFunc(
+		resource.TestCheckResourceAttrPair("data.zerotier_member.test", "id", "zerotier_member.car", "id"),
+		resource.TestCheckResourceAttr("data.zerotier_member.test", "member_id", "b0b5ca7000"),
+		resource.TestCheckResourceAttr("data.zerotier_member.test", "name", "bobs_car"),
+		resource.TestCheckResourceAttr("data.zerotier_member.test", "description", "bobs shiny car"),
+		resource.TestCheckResourceAttr("data.zerotier_member.test", "authorized", "true"),
+		resource.TestCheckResourceAttr("data.zerotier_member.test", "ipv4_assignments.0", "10.0.0.5"),
+		resource.TestCheckResourceAttr("data.zerotier_member.test", "online", "false"),
+		resource.TestCheckResourceAttrPair("data.zerotier_member.test", "rfc4193", "zerotier_member.car", "rfc4193"),
+	)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config(`member_id = "b0b5ca7000"`),
+				Check:  check,
+			},
+			{
+				Config: config(`name = "bobs_car"`),
+				Check:  check,
+			},
+			{
+				Config:      config(`member_id = "b0b5000000"`),
+				ExpectError: regexp.MustCompile(`Network [0-9a-f]{16} has no member b0b5000000`),
+			},
+			{
+				Config:      config(`name = "bobs_boat"`),
+				ExpectError: regexp.MustCompile(`Network [0-9a-f]{16} has no member named "bobs_boat"`),
+			},
+			{
+				Config:      config(`name = "bobs_ride"`),
+				ExpectError: regexp.MustCompile(`Network [0-9a-f]{16} has 2 members named "bobs_ride"\s+\(b0b55c0000,\s+b0b5b1ce00\)`),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceMember_lookup(t *testing.T) {
+	srv := testAccCentral(t)
+
+	for _, tc := range []struct {
+		lookup string
+		err    string
+	}{
+		{``, `"member_id": one of\s+` + "`member_id,name`" + `\s+must be specified`},
+		{"member_id = \"b0b5ca7000\"\n  name = \"bobs_car\"", `"member_id": only one of\s+` + "`member_id,name`" + `\s+can be specified`},
+	} {
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: testAccProviderFactories,
+			Steps: []resource.TestStep{
+				{
+					Config: testAccConfig(srv, fmt.Sprintf(`
+data "zerotier_member" "test" {
+  network_id = "8056c2e21c000001"
+  %s
+}
+`, tc.lookup)),
+					ExpectError: regexp.MustCompile(tc.err),
+				},
+			},
+		})
+	}
+}
@@ -4,17 +4,22 @@ import (
 	"context"
 	"crypto/md5"
 	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/zerotier/go-ztcentral"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
 )
 
 func dataSourceMembers() *schema.Resource {
 	return &schema.Resource{
-		Description: "Data source for ZeroTier members. This data source can be used to retrieve information about members of a ZeroTier network.",
+		Description: "Data source for ZeroTier members. This data source can be used to retrieve information about members of a ZeroTier network, optionally filtered. Members are sorted by ID.",
 		ReadContext: datasourceMemberRead,
 		Schema: map[string]*schema.Schema{
 			"network_id": {
@@ -22,6 +27,57 @@ func dataSourceMembers() *schema.Resource {
 				Required:    true,
 				Description: "ID of the network to retrieve members from.",
 			},
+			"online_threshold": onlineThresholdSchema(),
+			"authorized": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Only return members that are (true) or are not (false) authorized.",
+			},
+			"hidden": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Only return members that are (true) or are not (false) hidden.",
+			},
+			"online_within": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validDuration,
+				Description:      "Only return members that were online within this long, as a duration such as `24h`.",
+			},
+			"name_regex": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsValidRegExp),
+				Description:      "Only return members whose name matches this regular expression.",
+			},
+			"description_regex": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsValidRegExp),
+				Description:      "Only return members whose description matches this regular expression.",
+			},
+			"ip_in_cidr": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validCIDR,
+				Description:      "Only return members with an IP assignment inside this network, such as `10.0.0.0/24`.",
+			},
+			"tag_id": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Only return members that have the tag with this ID.",
+			},
+			"tag_value": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				RequiredWith: []string{"tag_id"},
+				Description:  "Only return members whose `tag_id` tag has this value.",
+			},
+			"capability_id": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Only return members that have the capability with this ID.",
+			},
 			"members": {
 				Type:     schema.TypeList,
 				Computed: true,
@@ -34,24 +90,41 @@ func dataSourceMembers() *schema.Resource {
 }
 
 func datasourceMemberRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	c := m.(*ztcentral.Client)
+	c := m.(backend)
 
 	nwid := d.Get("network_id").(string)
+	threshold := onlineThreshold(d)
+
+	filter, err := newMemberFilter(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 
 	networkMembers, err := c.GetMembers(ctx, nwid)
 	if err != nil {
 		return diag.FromErr(err)
 	}
+
+	// central lists members in no particular order.
+	networkMembers = append([]*spec.Member{}, networkMembers...)
+	sort.Slice(networkMembers, func(i, j int) bool {
+		return ptrString(networkMembers[i].NodeId) < ptrString(networkMembers[j].NodeId)
+	})
+
 	members := make([]map[string]interface{}, 0, len(networkMembers))
 	memberIDs := make([]string, 0, len(networkMembers))
 	for _, member := range networkMembers {
+		if !filter.matches(member) {
+			continue
+		}
+
 		ipv4Assignments, ipv6Assignments := assignedIpsGrouping(*member.Config.IpAssignments)
-		members = append(members, map[string]interface{}{
-			"name":                    *member.Name,
-			"description":             *member.Description,
+		attrs := map[string]interface{}{
+			"name":                    ptrString(member.Name),
+			"description":             ptrString(member.Description),
 			"member_id":               *member.NodeId,
 			"network_id":              *member.NetworkId,
-			"hidden":                  *member.Hidden,
+			"hidden":                  ptrBool(member.Hidden),
 			"authorized":              *member.Config.Authorized,
 			"sso_exempt":              *member.Config.SsoExempt,
 			"allow_ethernet_bridging": *member.Config.ActiveBridge,
@@ -63,17 +136,183 @@ func datasourceMemberRead(ctx context.Context, d *schema.ResourceData, m interfa
 			"ipv6_assignments":        ipv6Assignments,
 			"rfc4193":                 rfc4193Address(nwid, *member.NodeId),
 			"sixplane":                sixPlaneAddress(nwid, *member.NodeId),
-		})
+		}
+		for key, value := range memberPresence(member, threshold) {
+			attrs[key] = value
+		}
+
+		members = append(members, attrs)
 		memberIDs = append(memberIDs, *member.NodeId)
 	}
 	err = d.Set("members", members)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	d.SetId(stringChecksum(strings.Join(memberIDs, "")))
+	d.SetId(stringListChecksum(memberIDs))
 	return nil
 }
 
+// memberFilter is the filter arguments of the members data source. Unset
+// arguments are nil and match every member.
+type memberFilter struct {
+	authorized       *bool
+	hidden           *bool
+	onlineWithin     *time.Duration
+	nameRegex        *regexp.Regexp
+	descriptionRegex *regexp.Regexp
+	ipInCIDR         *netip.Prefix
+	tagID            *int
+	tagValue         *int
+	capabilityID     *int
+}
+
+func newMemberFilter(d *schema.ResourceData) (*memberFilter, error) {
+	f := &memberFilter{}
+
+	if configSet(d, "authorized") {
+		f.authorized = boolPtr(d.Get("authorized").(bool))
+	}
+
+	if configSet(d, "hidden") {
+		f.hidden = boolPtr(d.Get("hidden").(bool))
+	}
+
+	if configSet(d, "online_within") {
+		within, err := time.ParseDuration(d.Get("online_within").(string))
+		if err != nil {
+			return nil, fmt.Errorf("online_within: %w", err)
+		}
+		f.onlineWithin = &within
+	}
+
+	for attr, re := range map[string]**regexp.Regexp{"name_regex": &f.nameRegex, "description_regex": &f.descriptionRegex} {
+		if configSet(d, attr) {
+			compiled, err := regexp.Compile(d.Get(attr).(string))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", attr, err)
+			}
+			*re = compiled
+		}
+	}
+
+	if configSet(d, "ip_in_cidr") {
+		prefix, err := netip.ParsePrefix(d.Get("ip_in_cidr").(string))
+		if err != nil {
+			return nil, fmt.Errorf("ip_in_cidr: %w", err)
+		}
+		prefix = prefix.Masked()
+		f.ipInCIDR = &prefix
+	}
+
+	for attr, id := range map[string]**int{"tag_id": &f.tagID, "tag_value": &f.tagValue, "capability_id": &f.capabilityID} {
+		if configSet(d, attr) {
+			*id = intPtr(d.Get(attr).(int))
+		}
+	}
+
+	return f, nil
+}
+
+// configSet reports whether attr is set in the configuration of d. Unlike
+// d.GetOk, it tells an argument set to false or 0 from an unset one, as
+// filters need.
+func configSet(d *schema.ResourceData, attr string) bool {
+	config := d.GetRawConfig()
+	return !config.IsNull() && !config.GetAttr(attr).IsNull()
+}
+
+func (f *memberFilter) matches(m *spec.Member) bool {
+	config := m.Config
+	if config == nil {
+		config = &spec.MemberConfig{}
+	}
+
+	if f.authorized != nil && ptrBool(config.Authorized) != *f.authorized {
+		return false
+	}
+
+	if f.hidden != nil && ptrBool(m.Hidden) != *f.hidden {
+		return false
+	}
+
+	if f.onlineWithin != nil && !memberPresence(m, *f.onlineWithin)["online"].(bool) {
+		return false
+	}
+
+	if f.nameRegex != nil && !f.nameRegex.MatchString(ptrString(m.Name)) {
+		return false
+	}
+
+	if f.descriptionRegex != nil && !f.descriptionRegex.MatchString(ptrString(m.Description)) {
+		return false
+	}
+
+	if f.ipInCIDR != nil && !memberHasIPIn(config, *f.ipInCIDR) {
+		return false
+	}
+
+	if f.tagID != nil && !memberHasTag(config, *f.tagID, f.tagValue) {
+		return false
+	}
+
+	if f.capabilityID != nil && (config.Capabilities == nil || !slices.Contains(*config.Capabilities, *f.capabilityID)) {
+		return false
+	}
+
+	return true
+}
+
+func memberHasIPIn(config *spec.MemberConfig, prefix netip.Prefix) bool {
+	if config.IpAssignments == nil {
+		return false
+	}
+
+	for _, ip := range *config.IpAssignments {
+		if addr, err := netip.ParseAddr(ip); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// memberHasTag reports whether the member has the tag id, with value if it
+// is not nil. Tags are [id, value] pairs, decoded from JSON as numbers.
+func memberHasTag(config *spec.MemberConfig, id int, value *int) bool {
+	if config.Tags == nil {
+		return false
+	}
+
+	for _, tag := range *config.Tags {
+		if len(tag) != 2 {
+			continue
+		}
+
+		tagID, ok := tagNumber(tag[0])
+		if !ok || tagID != id {
+			continue
+		}
+
+		tagValue, ok := tagNumber(tag[1])
+		if value == nil || (ok && tagValue == *value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func tagNumber(i interface{}) (int, bool) {
+	switch n := i.(type) {
+	case float64:
+		return int(n), true
+	case int:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
 // stringChecksum takes a string and returns the checksum of the string.
 func stringChecksum(s string) string {
 	h := md5.New()
@@ -0,0 +1,345 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+func TestAccDataSourceMembers_basic(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "bobs_garage"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+}
+
+resource "zerotier_member" "car" {
+  network_id  = zerotier_network.test.id
+  member_id   = "b0b5ca7000"
+  name        = "bobs_car"
+  description = "bobs shiny car"
+}
+
+resource "zerotier_member" "bike" {
+  network_id     = zerotier_network.test.id
+  member_id      = "b0b5b1ce00"
+  name           = "bobs_bike"
+  ip_assignments = ["10.0.0.5"]
+}
+
+data "zerotier_members" "test" {
+  depends_on = [zerotier_member.car, zerotier_member.bike]
+  network_id = zerotier_network.test.id
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.zerotier_members.test", "id"),
+					resource.TestCheckResourceAttr("data.zerotier_members.test", "members.#", "2"),
+					resource.TestCheckTypeSetElemNestedAttrs("data.zerotier_members.test", "members.*", map[string]string{
+						"name":        "bobs_car",
+						"description": "bobs shiny car",
+						"member_id":   "b0b5ca7000",
+						"authorized":  "true",
+					}),
+					resource.TestCheckTypeSetElemNestedAttrs("data.zerotier_members.test", "members.*", map[string]string{
+						"name":               "bobs_bike",
+						"member_id":          "b0b5b1ce00",
+						"ip_assignments.#":   "1",
+						"ipv4_assignments.0": "10.0.0.5",
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestMemberFilter(t *testing.T) {
+	hourAgo := time.Now().Add(-time.Hour).UnixMilli()
+
+	m := &spec.Member{
+		Name:        stringPtr("bobs_car"),
+		Description: stringPtr("bobs shiny car"),
+		Hidden:      boolPtr(false),
+		LastOnline:  &hourAgo,
+		Config: &spec.MemberConfig{
+			Authorized:    boolPtr(true),
+			IpAssignments: &[]string{"10.0.0.5", "fd00::5"},
+			Tags:          &[][]interface{}{{float64(1), float64(2)}},
+			Capabilities:  &[]int{5},
+		},
+	}
+
+	prefix := func(s string) *netip.Prefix {
+		p := netip.MustParsePrefix(s)
+		return &p
+	}
+
+	within := func(d time.Duration) *time.Duration { return &d }
+
+	for _, tc := range []struct {
+		filter  memberFilter
+		matches bool
+	}{
+		{memberFilter{}, true},
+		{memberFilter{authorized: boolPtr(true)}, true},
+		{memberFilter{authorized: boolPtr(false)}, false},
+		{memberFilter{hidden: boolPtr(false)}, true},
+		{memberFilter{hidden: boolPtr(true)}, false},
+		{memberFilter{onlineWithin: within(2 * time.Hour)}, true},
+		{memberFilter{onlineWithin: within(time.Minute)}, false},
+		{memberFilter{nameRegex: regexp.MustCompile("^bobs_")}, true},
+		{memberFilter{nameRegex: regexp.MustCompile("bike")}, false},
+		{memberFilter{descriptionRegex: regexp.MustCompile("shiny")}, true},
+		{memberFilter{descriptionRegex: regexp.MustCompile("^$")}, false},
+		{memberFilter{ipInCIDR: prefix("10.0.0.0/24")}, true},
+		{memberFilter{ipInCIDR: prefix("fd00::/64")}, true},
+		{memberFilter{ipInCIDR: prefix("10.0.1.0/24")}, false},
+		{memberFilter{tagID: intPtr(1)}, true},
+		{memberFilter{tagID: intPtr(1), tagValue: intPtr(2)}, true},
+		{memberFilter{tagID: intPtr(1), tagValue: intPtr(0)}, false},
+		{memberFilter{tagID: intPtr(2)}, false},
+		{memberFilter{capabilityID: intPtr(5)}, true},
+		{memberFilter{capabilityID: intPtr(0)}, false},
+		{memberFilter{authorized: boolPtr(true), nameRegex: regexp.MustCompile("bike")}, false},
+	} {
+		assert.Equal(t, tc.matches, tc.filter.matches(m), "%+v", tc.filter)
+	}
+
+	// a member central reports nothing about matches only the empty filter.
+	assert.True(t, (&memberFilter{}).matches(&spec.Member{}))
+	assert.False(t, (&memberFilter{ipInCIDR: prefix("10.0.0.0/8")}).matches(&spec.Member{}))
+	assert.False(t, (&memberFilter{tagID: intPtr(1)}).matches(&spec.Member{}))
+}
+
+// shuffledMembers is a backend that lists members in a new order every time.
+type shuffledMembers struct {
+	backend
+}
+
+func (s shuffledMembers) GetMembers(ctx context.Context, networkID string) ([]*spec.Member, error) {
+	members, err := s.backend.GetMembers(ctx, networkID)
+	members = append([]*spec.Member{}, members...)
+	rand.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+	return members, err
+}
+
+func TestDataSourceMembers_StableOrder(t *testing.T) {
+	_, c, nwid, ids := testMemberCache(t, 10)
+
+	read := func() (string, []string) {
+		d := schema.TestResourceDataRaw(t, dataSourceMembers().Schema, map[string]interface{}{
+			"network_id": nwid,
+		})
+
+		diags := datasourceMemberRead(context.Background(), d, shuffledMembers{c})
+		assert.False(t, diags.HasError(), "%v", diags)
+
+		memberIDs := []string{}
+		for _, member := range d.Get("members").([]interface{}) {
+			memberIDs = append(memberIDs, member.(map[string]interface{})["member_id"].(string))
+		}
+
+		return d.Id(), memberIDs
+	}
+
+	id, memberIDs := read()
+	assert.Equal(t, ids, memberIDs)
+
+	for i := 0; i < 5; i++ {
+		again, againIDs := read()
+		assert.Equal(t, id, again)
+		assert.Equal(t, ids, againIDs)
+	}
+}
+
+func TestAccDataSourceMembers_filters(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "bobs_garage"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+}
+
+resource "zerotier_member" "car" {
+  network_id   = zerotier_network.test.id
+  member_id    = "b0b5ca7000"
+  name         = "bobs_car"
+  description  = "bobs shiny car"
+  tags         = [[1, 2]]
+  capabilities = [5]
+}
+
+resource "zerotier_member" "bike" {
+  network_id     = zerotier_network.test.id
+  member_id      = "b0b5b1ce00"
+  name           = "bobs_bike"
+  hidden         = true
+  ip_assignments = ["10.0.0.5"]
+  tags           = [[1, 3]]
+}
+
+resource "zerotier_member" "van" {
+  network_id = zerotier_network.test.id
+  member_id  = "a11ce0a000"
+  name       = "alices_van"
+  authorized = false
+}
+
+locals {
+  members = [zerotier_member.car, zerotier_member.bike, zerotier_member.van]
+}
+
+data "zerotier_members" "all" {
+  depends_on = [local.members]
+  network_id = zerotier_network.test.id
+}
+
+data "zerotier_members" "unauthorized" {
+  depends_on = [local.members]
+  network_id = zerotier_network.test.id
+  authorized = false
+}
+
+data "zerotier_members" "visible" {
+  depends_on = [local.members]
+  network_id = zerotier_network.test.id
+  hidden     = false
+}
+
+data "zerotier_members" "bobs" {
+  depends_on = [local.members]
+  network_id = zerotier_network.test.id
+  name_regex = "^bobs_"
+}
+
+data "zerotier_members" "shiny" {
+  depends_on        = [local.members]
+  network_id        = zerotier_network.test.id
+  description_regex = "shiny"
+}
+
+data "zerotier_members" "addressed" {
+  depends_on = [local.members]
+  network_id = zerotier_network.test.id
+  ip_in_cidr = "10.0.0.0/24"
+}
+
+data "zerotier_members" "tagged" {
+  depends_on = [local.members]
+  network_id = zerotier_network.test.id
+  tag_id     = 1
+}
+
+data "zerotier_members" "tag_value" {
+  depends_on = [local.members]
+  network_id = zerotier_network.test.id
+  tag_id     = 1
+  tag_value  = 3
+}
+
+data "zerotier_members" "capable" {
+  depends_on    = [local.members]
+  network_id    = zerotier_network.test.id
+  capability_id = 5
+}
+
+data "zerotier_members" "online" {
+  depends_on    = [local.members]
+  network_id    = zerotier_network.test.id
+  online_within = "24h"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					// sorted by member ID, whatever the filter.
+					resource.TestCheckResourceAttr("data.zerotier_members.all", "members.#", "3"),
+					resource.TestCheckResourceAttr("data.zerotier_members.all", "members.0.member_id", "a11ce0a000"),
+					resource.TestCheckResourceAttr("data.zerotier_members.all", "members.1.member_id", "b0b5b1ce00"),
+					resource.TestCheckResourceAttr("data.zerotier_members.all", "members.2.member_id", "b0b5ca7000"),
+
+					testAccCheckMemberIDs("unauthorized", "a11ce0a000"),
+					testAccCheckMemberIDs("visible", "a11ce0a000", "b0b5ca7000"),
+					testAccCheckMemberIDs("bobs", "b0b5b1ce00", "b0b5ca7000"),
+					testAccCheckMemberIDs("shiny", "b0b5ca7000"),
+					testAccCheckMemberIDs("addressed", "b0b5b1ce00"),
+					testAccCheckMemberIDs("tagged", "b0b5b1ce00", "b0b5ca7000"),
+					testAccCheckMemberIDs("tag_value", "b0b5b1ce00"),
+					testAccCheckMemberIDs("capable", "b0b5ca7000"),
+					testAccCheckMemberIDs("online"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceMembers_invalidFilters(t *testing.T) {
+	srv := testAccCentral(t)
+
+	for _, tc := range []struct {
+		arg string
+		err string
+	}{
+		{`name_regex = "("`, `"name_regex": error parsing regexp`},
+		{`ip_in_cidr = "10.0.0.0"`, `expected "ip_in_cidr" to be a valid CIDR Value`},
+		{`online_within = "soon"`, `invalid duration`},
+		{`tag_value = 1`, `"tag_value": all of\s+` + "`tag_id,tag_value`" + `\s+must be specified`},
+	} {
+		t.Run(tc.arg, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				ProviderFactories: testAccProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: testAccConfig(srv, fmt.Sprintf(`
+data "zerotier_members" "test" {
+  network_id = "8056c2e21c000001"
+  %s
+}
+`, tc.arg)),
+						ExpectError: regexp.MustCompile(tc.err),
+					},
+				},
+			})
+		})
+	}
+}
+
+// testAccCheckMemberIDs checks that the members data source name lists
+// exactly the members ids, in order.
+func testAccCheckMemberIDs(name string, ids ...string) resource.TestCheckFunc {
+	addr := "data.zerotier_members." + name
+
+	checks := []resource.TestCheckFunc{
+		resource.TestCheckResourceAttr(addr, "members.#", strconv.Itoa(len(ids))),
+	}
+	for i, id := range ids {
+		checks = append(checks, resource.TestCheckResourceAttr(addr, fmt.Sprintf("members.%d.member_id", i), id))
+	}
+
+	return resource.ComposeTestCheckFunc(checks...)
+}
@@ -3,35 +3,182 @@ package zerotier
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/zerotier/go-ztcentral"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
 )
 
+// networkLookups are the arguments the network data source can find a
+// network by.
+var networkLookups = []string{"id", "name", "name_regex"}
+
 func dataSourceNetwork() *schema.Resource {
 	return &schema.Resource{
-		Description: "Data source for ZeroTier networks, allowing you to find a network by ID",
+		Description: "Data source for ZeroTier networks, allowing you to find a network by ID, by name, or by a regular expression of its name.",
 		ReadContext: dataSourceNetworkRead,
-		Schema:      NetworkSchema,
+		Schema:      dataSourceNetworkSchema(),
+	}
+}
+
+// networkAttributesSchema is NetworkSchema computed, without the blocks that
+// are only shorthand for configuring a network.
+func networkAttributesSchema() map[string]*schema.Schema {
+	s := computedSchema(NetworkSchema)
+	for _, key := range []string{"subnet", "rule", "tag", "capability"} {
+		delete(s, key)
+	}
+
+	return s
+}
+
+func dataSourceNetworkSchema() map[string]*schema.Schema {
+	s := networkAttributesSchema()
+
+	s["id"].Optional = true
+	s["id"].Description = "ID of the network to find."
+	s["name"].Optional = true
+	s["name"].Description = "Name of the network to find. Exactly one network visible to the token may have it."
+	s["name_regex"] = &schema.Schema{
+		Type:             schema.TypeString,
+		Optional:         true,
+		ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsValidRegExp),
+		Description:      "Regular expression of the name of the network to find. Exactly one network visible to the token may match it.",
+	}
+
+	for _, key := range networkLookups {
+		s[key].ExactlyOneOf = networkLookups
+	}
+
+	return s
+}
+
+// computedSchema returns a copy of s with every attribute, down to those of
+// its blocks, computed only, for data sources to read into.
+func computedSchema(s map[string]*schema.Schema) map[string]*schema.Schema {
+	res := map[string]*schema.Schema{}
+	for key, value := range s {
+		res[key] = &schema.Schema{
+			Type:        value.Type,
+			Computed:    true,
+			Elem:        computedElem(value.Elem),
+			Set:         value.Set,
+			Description: value.Description,
+		}
+	}
+
+	return res
+}
+
+func computedElem(elem interface{}) interface{} {
+	switch e := elem.(type) {
+	case *schema.Resource:
+		return &schema.Resource{Schema: computedSchema(e.Schema), Description: e.Description}
+	case *schema.Schema:
+		return &schema.Schema{Type: e.Type, Elem: computedElem(e.Elem)}
+	default:
+		return elem
 	}
 }
 
 func dataSourceNetworkRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	c := m.(*ztcentral.Client)
-	var diags diag.Diagnostics
+	c := m.(backend)
+
+	var (
+		ztNetwork *spec.Network
+		diags     diag.Diagnostics
+	)
+
+	if ztNetworkID := d.Get("id").(string); ztNetworkID != "" {
+		ztNetwork, diags = findNetworkByID(ctx, c, ztNetworkID)
+	} else {
+		ztNetwork, diags = findNetworkByName(ctx, c, d.Get("name").(string), d.Get("name_regex").(string))
+	}
+
+	if diags.HasError() {
+		return diags
+	}
+
+	return networkToTerraform(d, ztNetwork)
+}
 
-	ztNetworkID := d.Get("id").(string)
+func findNetworkByID(ctx context.Context, c backend, id string) (*spec.Network, diag.Diagnostics) {
+	ztNetwork, err := c.GetNetwork(ctx, id)
+	if isNotFound(err) {
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "ZeroTier Network not found",
+			Detail:   fmt.Sprintf("Network %s does not exist or is not visible to the token.", id),
+		}}
+	}
 
-	ztNetwork, err := c.GetNetwork(ctx, ztNetworkID)
 	if err != nil {
-		diags = append(diags, diag.Diagnostic{
+		return nil, diag.Diagnostics{{
 			Severity: diag.Error,
 			Summary:  "Unable to read ZeroTier Network",
 			Detail:   fmt.Sprintf("GetNetwork returned error: %v", err),
-		})
-		return diags
+		}}
 	}
 
-	return networkToTerraform(d, ztNetwork)
+	return ztNetwork, nil
+}
+
+// findNetworkByName finds the one network named name or, if it is empty,
+// whose name matches nameRegex.
+func findNetworkByName(ctx context.Context, c backend, name, nameRegex string) (*spec.Network, diag.Diagnostics) {
+	desc := fmt.Sprintf("named %q", name)
+	matches := func(n string) bool { return n == name }
+
+	if name == "" {
+		re, err := regexp.Compile(nameRegex)
+		if err != nil {
+			return nil, diag.FromErr(fmt.Errorf("name_regex: %w", err))
+		}
+
+		desc = fmt.Sprintf("with a name matching %q", nameRegex)
+		matches = re.MatchString
+	}
+
+	ztNetworks, err := c.GetNetworks(ctx)
+	if err != nil {
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Unable to read ZeroTier Networks",
+			Detail:   fmt.Sprintf("GetNetworks returned error: %v", err),
+		}}
+	}
+
+	found := []*spec.Network{}
+	for _, n := range ztNetworks {
+		if n.Config != nil && matches(ptrString(n.Config.Name)) {
+			found = append(found, n)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "ZeroTier Network not found",
+			Detail:   fmt.Sprintf("No network %s is visible to the token.", desc),
+		}}
+	case 1:
+		return found[0], nil
+	default:
+		ids := []string{}
+		for _, n := range found {
+			ids = append(ids, *n.Id)
+		}
+		sort.Strings(ids)
+
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Multiple ZeroTier Networks found",
+			Detail:   fmt.Sprintf("%d networks %s are visible to the token (%s). Look the network up by id instead.", len(found), desc, strings.Join(ids, ", ")),
+		}}
+	}
 }
@@ -0,0 +1,157 @@
+package zerotier
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccDataSourceNetwork_basic(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name        = "bobs_garage"
+  description = "so say we bob"
+  flow_rules  = "drop;"
+}
+
+data "zerotier_network" "test" {
+  id = zerotier_network.test.id
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair("data.zerotier_network.test", "id", "zerotier_network.test", "id"),
+					resource.TestCheckResourceAttr("data.zerotier_network.test", "name", "bobs_garage"),
+					resource.TestCheckResourceAttr("data.zerotier_network.test", "description", "so say we bob"),
+					resource.TestCheckResourceAttr("data.zerotier_network.test", "flow_rules", "drop;"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceNetworkSchema(t *testing.T) {
+	var check func(path string, s map[string]*schema.Schema)
+	check = func(path string, s map[string]*schema.Schema) {
+		for key, value := range s {
+			if path == "" && slices.Contains(networkLookups, key) {
+				continue
+			}
+
+			assert.True(t, value.Computed, "%s%s is not computed", path, key)
+			assert.False(t, value.Optional || value.Required, "%s%s can be configured", path, key)
+			assert.Nil(t, value.Default, "%s%s has a default", path, key)
+
+			if r, ok := value.Elem.(*schema.Resource); ok {
+				check(path+key+".", r.Schema)
+			}
+		}
+	}
+
+	s := dataSourceNetworkSchema()
+	check("", s)
+	assert.NotContains(t, s, "subnet")
+	assert.NoError(t, schema.InternalMap(s).InternalValidate(nil))
+}
+
+func TestAccDataSourceNetwork_byName(t *testing.T) {
+	srv := testAccCentral(t)
+
+	config := func(lookup string) string {
+		return testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_network" "garage" {
+  name        = "bobs_garage"
+  description = "so say we bob"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+}
+
+resource "zerotier_network" "shed" {
+  name = "bobs_shed"
+}
+
+data "zerotier_network" "test" {
+  depends_on = [zerotier_network.garage, zerotier_network.shed]
+  %s
+}
+`, lookup))
+	}
+
+	check := resource.ComposeTestCheckFunc(
+		resource.TestCheckResourceAttrPair("data.zerotier_network.test", "id", "zerotier_network.garage", "id"),
+		resource.TestCheckResourceAttr("data.zerotier_network.test", "name", "bobs_garage"),
+		resource.TestCheckResourceAttr("data.zerotier_network.test", "description", "so say we bob"),
+		resource.TestCheckResourceAttr("data.zerotier_network.test", "route.#", "1"),
+		resource.TestCheckResourceAttr("data.zerotier_network.test", "private", "true"),
+	)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config(`name = "bobs_garage"`),
+				Check:  check,
+			},
+			{
+				Config: config(`name_regex = "gar"`),
+				Check:  check,
+			},
+			{
+				Config:      config(`name = "bobs"`),
+				ExpectError: regexp.MustCompile(`No network named "bobs" is visible to the token`),
+			},
+			{
+				Config:      config(`name_regex = "^alices_"`),
+				ExpectError: regexp.MustCompile(`No network with a name matching "\^alices_" is\s+visible\s+to\s+the\s+token`),
+			},
+			{
+				Config:      config(`name_regex = "^bobs_"`),
+				ExpectError: regexp.MustCompile(`2 networks with a name matching "\^bobs_" are\s+visible\s+to\s+the\s+token\s+\([0-9a-f]{16},\s+[0-9a-f]{16}\)`),
+			},
+			{
+				Config:      config(`id = "8056c2e21c000001"`),
+				ExpectError: regexp.MustCompile(`Network 8056c2e21c000001 does not exist or is not visible to the token`),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceNetwork_lookup(t *testing.T) {
+	srv := testAccCentral(t)
+
+	for _, tc := range []struct {
+		lookup string
+		err    string
+	}{
+		{``, `"id": one of\s+` + "`id,name,name_regex`" + `\s+must be specified`},
+		{"name = \"bobs_garage\"\n  name_regex = \"bobs\"", `"name": only one of\s+` + "`id,name,name_regex`" + `\s+can be specified`},
+		{`name_regex = "("`, `"name_regex": error parsing regexp`},
+		{`description = "bobs"`, `Can't configure a value for "description"`},
+	} {
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: testAccProviderFactories,
+			Steps: []resource.TestStep{
+				{
+					Config: testAccConfig(srv, fmt.Sprintf(`
+data "zerotier_network" "test" {
+  %s
+}
+`, tc.lookup)),
+					ExpectError: regexp.MustCompile(tc.err),
+				},
+			},
+		})
+	}
+}
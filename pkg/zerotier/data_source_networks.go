@@ -0,0 +1,149 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+func dataSourceNetworks() *schema.Resource {
+	return &schema.Resource{
+		Description: "Data source for the ZeroTier networks visible to the token, optionally filtered. Networks are sorted by ID.",
+		ReadContext: dataSourceNetworksRead,
+		Schema: map[string]*schema.Schema{
+			"name_regex": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsValidRegExp),
+				Description:      "Only return networks whose name matches this regular expression.",
+			},
+			"description_regex": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsValidRegExp),
+				Description:      "Only return networks whose description matches this regular expression.",
+			},
+			"private": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Only return networks that are (true) or are not (false) private.",
+			},
+			"ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "IDs of the networks.",
+			},
+			"networks": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: networkAttributesSchema(),
+				},
+				Description: "The networks, with the attributes of the `zerotier_network` data source.",
+			},
+		},
+	}
+}
+
+func dataSourceNetworksRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	c := m.(backend)
+
+	matches, err := networksFilter(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	ztNetworks, err := c.GetNetworks(ctx)
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Unable to read ZeroTier Networks",
+			Detail:   fmt.Sprintf("GetNetworks returned error: %v", err),
+		}}
+	}
+
+	// central lists networks in no particular order.
+	ztNetworks = append([]*spec.Network{}, ztNetworks...)
+	sort.Slice(ztNetworks, func(i, j int) bool {
+		return ptrString(ztNetworks[i].Id) < ptrString(ztNetworks[j].Id)
+	})
+
+	attrsSchema := networkAttributesSchema()
+
+	networks := []map[string]interface{}{}
+	ids := []string{}
+	for _, n := range ztNetworks {
+		if n.Config == nil || !matches(n) {
+			continue
+		}
+
+		// read the network as the network data source would.
+		nd := (&schema.Resource{Schema: attrsSchema}).Data(nil)
+		if diags := networkToTerraform(nd, n); diags.HasError() {
+			return diags
+		}
+
+		attrs := map[string]interface{}{}
+		for key := range attrsSchema {
+			attrs[key] = nd.Get(key)
+		}
+		attrs["id"] = nd.Id()
+
+		networks = append(networks, attrs)
+		ids = append(ids, nd.Id())
+	}
+
+	if err := d.Set("networks", networks); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("ids", ids); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(stringListChecksum(ids))
+
+	return nil
+}
+
+// networksFilter returns whether a network matches the filter arguments of
+// d. Unset arguments match every network.
+func networksFilter(d *schema.ResourceData) (func(n *spec.Network) bool, error) {
+	filters := []func(n *spec.Network) bool{}
+
+	if configSet(d, "name_regex") {
+		re, err := regexp.Compile(d.Get("name_regex").(string))
+		if err != nil {
+			return nil, fmt.Errorf("name_regex: %w", err)
+		}
+		filters = append(filters, func(n *spec.Network) bool { return re.MatchString(ptrString(n.Config.Name)) })
+	}
+
+	if configSet(d, "description_regex") {
+		re, err := regexp.Compile(d.Get("description_regex").(string))
+		if err != nil {
+			return nil, fmt.Errorf("description_regex: %w", err)
+		}
+		filters = append(filters, func(n *spec.Network) bool { return re.MatchString(ptrString(n.Description)) })
+	}
+
+	if configSet(d, "private") {
+		private := d.Get("private").(bool)
+		filters = append(filters, func(n *spec.Network) bool { return ptrBool(n.Config.Private) == private })
+	}
+
+	return func(n *spec.Network) bool {
+		for _, f := range filters {
+			if !f(n) {
+				return false
+			}
+		}
+
+		return true
+	}, nil
+}
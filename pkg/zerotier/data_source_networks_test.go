@@ -0,0 +1,94 @@
+package zerotier
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceNetworks_filters(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "garage" {
+  name        = "bobs_garage"
+  description = "so say we bob"
+  flow_rules  = "drop;"
+}
+
+resource "zerotier_network" "shed" {
+  name    = "bobs_shed"
+  private = false
+}
+
+resource "zerotier_network" "office" {
+  name = "alices_office"
+}
+
+locals {
+  networks = [zerotier_network.garage, zerotier_network.shed, zerotier_network.office]
+}
+
+data "zerotier_networks" "all" {
+  depends_on = [local.networks]
+}
+
+data "zerotier_networks" "bobs" {
+  depends_on = [local.networks]
+  name_regex = "^bobs_"
+}
+
+data "zerotier_networks" "public" {
+  depends_on = [local.networks]
+  private    = false
+}
+
+data "zerotier_networks" "private_bobs" {
+  depends_on = [local.networks]
+  name_regex = "^bobs_"
+  private    = true
+}
+
+data "zerotier_networks" "described" {
+  depends_on        = [local.networks]
+  description_regex = "bob$"
+}
+
+data "zerotier_networks" "none" {
+  depends_on = [local.networks]
+  name_regex = "^carols_"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.zerotier_networks.all", "ids.#", "3"),
+					resource.TestCheckResourceAttr("data.zerotier_networks.all", "networks.#", "3"),
+					resource.TestCheckTypeSetElemAttrPair("data.zerotier_networks.all", "ids.*", "zerotier_network.office", "id"),
+
+					resource.TestCheckResourceAttr("data.zerotier_networks.bobs", "ids.#", "2"),
+					resource.TestCheckTypeSetElemAttrPair("data.zerotier_networks.bobs", "ids.*", "zerotier_network.garage", "id"),
+					resource.TestCheckTypeSetElemAttrPair("data.zerotier_networks.bobs", "ids.*", "zerotier_network.shed", "id"),
+
+					resource.TestCheckResourceAttr("data.zerotier_networks.public", "ids.#", "1"),
+					resource.TestCheckResourceAttrPair("data.zerotier_networks.public", "ids.0", "zerotier_network.shed", "id"),
+					resource.TestCheckResourceAttr("data.zerotier_networks.public", "networks.0.name", "bobs_shed"),
+					resource.TestCheckResourceAttr("data.zerotier_networks.public", "networks.0.private", "false"),
+
+					resource.TestCheckResourceAttr("data.zerotier_networks.private_bobs", "ids.#", "1"),
+					resource.TestCheckResourceAttrPair("data.zerotier_networks.private_bobs", "ids.0", "zerotier_network.garage", "id"),
+
+					resource.TestCheckResourceAttr("data.zerotier_networks.described", "ids.#", "1"),
+					resource.TestCheckResourceAttrPair("data.zerotier_networks.described", "networks.0.id", "zerotier_network.garage", "id"),
+					resource.TestCheckResourceAttr("data.zerotier_networks.described", "networks.0.description", "so say we bob"),
+					resource.TestCheckResourceAttr("data.zerotier_networks.described", "networks.0.flow_rules", "drop;"),
+
+					resource.TestCheckResourceAttr("data.zerotier_networks.none", "ids.#", "0"),
+					resource.TestCheckResourceAttr("data.zerotier_networks.none", "networks.#", "0"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,115 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// maxListedOnlineMembers is how many online members a refused network
+// deletion names.
+const maxListedOnlineMembers = 5
+
+func deletionProtectionSchema(kind string) *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: fmt.Sprintf("Refuse to delete the %s, including to replace it. Set it to false and apply before destroying the %s.", kind, kind),
+	}
+}
+
+// resourceNetworkSchema is NetworkSchema with the attributes that only make
+// sense for a network Terraform manages.
+func resourceNetworkSchema() map[string]*schema.Schema {
+	res := map[string]*schema.Schema{}
+	for key, value := range NetworkSchema {
+		res[key] = value
+	}
+
+	res["deletion_protection"] = deletionProtectionSchema("network")
+	res["prevent_deletion_if_online_within"] = &schema.Schema{
+		Type:             schema.TypeString,
+		Optional:         true,
+		ValidateDiagFunc: validDuration,
+		Description:      "Refuse to delete the network while any of its members was online within this long, as a duration such as `24h`. On backends that don't report member presence, the network can only be deleted once it has no members.",
+	}
+
+	return res
+}
+
+// checkDeletionProtection refuses to delete what d describes if its
+// deletion_protection is set.
+func checkDeletionProtection(d *schema.ResourceData, kind, name string) diag.Diagnostics {
+	if !d.Get("deletion_protection").(bool) {
+		return nil
+	}
+
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  fmt.Sprintf("ZeroTier %s is protected from deletion", kind),
+		Detail:   fmt.Sprintf("%s %s has deletion_protection set. Set it to false and apply before destroying or replacing it.", kind, name),
+	}}
+}
+
+// checkNoOnlineMembers refuses to delete the network nwid while one of its
+// members was online within prevent_deletion_if_online_within.
+func checkNoOnlineMembers(ctx context.Context, c backend, d *schema.ResourceData, nwid string) diag.Diagnostics {
+	s := d.Get("prevent_deletion_if_online_within").(string)
+	if s == "" {
+		return nil
+	}
+
+	window, err := time.ParseDuration(s)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	members, err := c.GetMembers(ctx, nwid)
+	if err != nil {
+		return diag.Errorf("unable to check network %s for online members: %v", nwid, err)
+	}
+
+	since := time.Now().Add(-window).UnixMilli()
+
+	online := []string{}
+	reported := false
+	for _, member := range members {
+		reported = reported || member.LastOnline != nil
+		if member.LastOnline != nil && *member.LastOnline >= since {
+			online = append(online, ptrString(member.NodeId))
+		}
+	}
+
+	// without presence, every member would look offline.
+	if len(members) > 0 && !reported {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Unable to check ZeroTier Network for online members",
+			Detail:   fmt.Sprintf("Network %s has %d members, but the backend does not report member presence, so prevent_deletion_if_online_within can't be checked. Unset it or remove the members before destroying the network.", nwid, len(members)),
+		}}
+	}
+
+	if len(online) == 0 {
+		return nil
+	}
+
+	sort.Strings(online)
+
+	listed := online
+	if len(listed) > maxListedOnlineMembers {
+		listed = append(listed[:maxListedOnlineMembers:maxListedOnlineMembers], "...")
+	}
+
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  "ZeroTier Network still has online members",
+		Detail: fmt.Sprintf("Network %s has %d members that were online within %s (%s), and prevent_deletion_if_online_within is set. Remove the members or wait for them to go offline before destroying it.",
+			nwid, len(online), window, strings.Join(listed, ", ")),
+	}}
+}
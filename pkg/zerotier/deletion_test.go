@@ -0,0 +1,175 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+func TestCheckNoOnlineMembers(t *testing.T) {
+	srv, c, nwid, ids := testMemberCache(t, 8)
+
+	now := time.Now().UnixMilli()
+	for i, id := range ids {
+		// the first six were online a minute ago, the rest two days ago.
+		lastOnline := now - time.Minute.Milliseconds()
+		if i >= 6 {
+			lastOnline = now - 48*time.Hour.Milliseconds()
+		}
+
+		srv.SetMember(nwid, id, &spec.Member{LastOnline: &lastOnline})
+	}
+
+	check := func(window string) error {
+		d := schema.TestResourceDataRaw(t, resourceNetworkSchema(), map[string]interface{}{
+			"prevent_deletion_if_online_within": window,
+		})
+
+		diags := checkNoOnlineMembers(context.Background(), c, d, nwid)
+		if diags.HasError() {
+			return fmt.Errorf("%s", diags[0].Detail)
+		}
+
+		return nil
+	}
+
+	assert.NoError(t, check(""))
+	assert.NoError(t, check("30s"))
+	assert.EqualError(t, check("1h"), fmt.Sprintf("Network %s has 6 members that were online within 1h0m0s (%s, %s, %s, %s, %s, ...), and prevent_deletion_if_online_within is set. Remove the members or wait for them to go offline before destroying it.",
+		nwid, ids[0], ids[1], ids[2], ids[3], ids[4]))
+	assert.ErrorContains(t, check("72h"), "has 8 members")
+}
+
+func TestCheckNoOnlineMembers_NoPresence(t *testing.T) {
+	_, c := testLocalBackend(t)
+	ctx := context.Background()
+
+	n, err := c.NewNetwork(ctx, "presence", &spec.Network{})
+	assert.NoError(t, err)
+
+	check := func(window string) error {
+		d := schema.TestResourceDataRaw(t, resourceNetworkSchema(), map[string]interface{}{
+			"prevent_deletion_if_online_within": window,
+		})
+
+		diags := checkNoOnlineMembers(ctx, c, d, *n.Id)
+		if diags.HasError() {
+			return fmt.Errorf("%s", diags[0].Detail)
+		}
+
+		return nil
+	}
+
+	// there is nothing to protect without members.
+	assert.NoError(t, check("1h"))
+
+	_, err = c.CreateAuthorizedMember(ctx, *n.Id, "a1b2c3d4e5", "")
+	assert.NoError(t, err)
+
+	assert.NoError(t, check(""))
+	assert.ErrorContains(t, check("1h"), "has 1 members, but the backend does not report member presence")
+}
+
+func TestAccNetwork_deletionProtection(t *testing.T) {
+	srv := testAccCentral(t)
+
+	config := func(network, member bool) string {
+		return testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_network" "test" {
+  name                = "acc-network-protected"
+  deletion_protection = %t
+}
+
+resource "zerotier_member" "test" {
+  network_id          = zerotier_network.test.id
+  member_id           = "a1b2c3d4e5"
+  deletion_protection = %t
+}
+`, network, member))
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy: resource.ComposeTestCheckFunc(
+			testAccCheckMemberDestroyed(srv),
+			testAccCheckNetworkDestroyed(srv),
+		),
+		Steps: []resource.TestStep{
+			{
+				Config: config(true, true),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_network.test", "deletion_protection", "true"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "deletion_protection", "true"),
+				),
+			},
+			{
+				Config:      config(true, true),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile(`Member [0-9a-f]{16}/a1b2c3d4e5 has deletion_protection set`),
+			},
+			{
+				Config: config(true, false),
+			},
+			{
+				Config:      config(true, false),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile(`Network [0-9a-f]{16} has deletion_protection set`),
+			},
+			{
+				Config: config(false, false),
+				Check:  resource.TestCheckResourceAttr("zerotier_network.test", "deletion_protection", "false"),
+			},
+		},
+	})
+}
+
+func TestAccNetwork_preventDeletionIfOnline(t *testing.T) {
+	srv := testAccCentral(t)
+
+	config := testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name                              = "acc-network-online"
+  prevent_deletion_if_online_within = "1h"
+}
+`)
+
+	var id string
+
+	setLastOnline := func(ago time.Duration) {
+		lastOnline := time.Now().Add(-ago).UnixMilli()
+		srv.SetMember(id, "a1b2c3d4e5", &spec.Member{LastOnline: &lastOnline})
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckNetworkDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: func(s *terraform.State) error {
+					id = s.RootModule().Resources["zerotier_network.test"].Primary.ID
+					return nil
+				},
+			},
+			{
+				PreConfig:   func() { setLastOnline(time.Minute) },
+				Config:      config,
+				Destroy:     true,
+				ExpectError: regexp.MustCompile(`has 1 members that were online within 1h0m0s\s+\(a1b2c3d4e5\)`),
+			},
+			{
+				// once the member has been offline long enough, it goes.
+				PreConfig: func() { setLastOnline(2 * time.Hour) },
+				Config:    config,
+			},
+		},
+	})
+}
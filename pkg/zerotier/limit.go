@@ -0,0 +1,62 @@
+package zerotier
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// limitTransport caps the number of requests in flight at once, shared by
+// every resource the provider is managing. Without it, a high
+// -parallelism has the API throttle us. A slot is held until the response
+// body is closed, not just until the headers arrive.
+type limitTransport struct {
+	next http.RoundTripper
+	sem  chan struct{}
+}
+
+// newLimitTransport returns next limited to max concurrent requests. If max
+// is 0, next is returned as is.
+func newLimitTransport(next http.RoundTripper, max int) http.RoundTripper {
+	if max <= 0 {
+		return next
+	}
+
+	return &limitTransport{
+		next: next,
+		sem:  make(chan struct{}, max),
+	}
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	release := func() { <-t.sem }
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+
+	return resp, nil
+}
+
+// releaseBody gives back the slot of a limitTransport when it is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
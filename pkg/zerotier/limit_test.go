@@ -0,0 +1,109 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimit_Backend(t *testing.T) {
+	for limit, check := range map[int]func(t *testing.T, inFlight int){
+		1: func(t *testing.T, inFlight int) { assert.Equal(t, 1, inFlight) },
+		3: func(t *testing.T, inFlight int) { assert.LessOrEqual(t, inFlight, 3) },
+		// without a limit, the fake sees everything at once.
+		0: func(t *testing.T, inFlight int) { assert.Greater(t, inFlight, 3) },
+	} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			srv := testAccCentral(t)
+			srv.SetLatency(20 * time.Millisecond)
+
+			c, err := newCentralBackend(srv.URL(), srv.Token, newLimitTransport(http.DefaultTransport, limit))
+			assert.NoError(t, err)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := c.GetNetworks(context.Background())
+					assert.NoError(t, err)
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, 20, srv.Requests())
+			check(t, srv.MaxInFlight())
+		})
+	}
+}
+
+func TestLimit_HeldUntilBodyIsClosed(t *testing.T) {
+	srv := testAccCentral(t)
+	transport := newLimitTransport(http.DefaultTransport, 1)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL()+"/status", nil)
+	assert.NoError(t, err)
+
+	resp, err := transport.RoundTrip(req)
+	assert.NoError(t, err)
+
+	// the only slot is taken until the body is closed.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = transport.RoundTrip(req.WithContext(ctx))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.NoError(t, resp.Body.Close())
+	assert.NoError(t, resp.Body.Close())
+
+	resp, err = transport.RoundTrip(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, 2, srv.Requests())
+}
+
+func TestAccLimit_parallelMembers(t *testing.T) {
+	srv := testAccCentral(t)
+	srv.SetLatency(10 * time.Millisecond)
+
+	t.Setenv("ZEROTIER_MAX_CONCURRENT_REQUESTS", "2")
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-limit"
+}
+
+resource "zerotier_member" "test" {
+  count      = 20
+  network_id = zerotier_network.test.id
+  member_id  = format("a1b2c3d4%02x", count.index)
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_member.test.19", "member_id", "a1b2c3d413"),
+					func(*terraform.State) error {
+						if inFlight := srv.MaxInFlight(); inFlight > 2 {
+							return fmt.Errorf("%d requests were in flight at once, expected at most 2", inFlight)
+						}
+
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/zerotier/go-ztcentral/pkg/spec"
 )
 
@@ -67,7 +68,7 @@ func buildMemberSchema(asResource bool) map[string]*schema.Schema {
 			Elem: &schema.Schema{
 				Type: schema.TypeString,
 			},
-			Description: "List of IP address assignments",
+			Description: "List of IP address assignments. Each must be inside one of the network's routes.",
 		},
 		"capabilities": {
 			Type:     schema.TypeSet,
@@ -127,17 +128,58 @@ func buildMemberSchema(asResource bool) map[string]*schema.Schema {
 			Description: "Is the member exempt from SSO?",
 		},
 	}
+	for key, value := range memberPresenceSchema {
+		start[key] = value
+	}
+
 	if asResource {
+		start["online_threshold"] = onlineThresholdSchema()
+		start["wait_for_online"] = &schema.Schema{
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "On create, wait until the member is `online`, for as long as the create timeout allows.",
+		}
+		start["wait_for_ip_assignment"] = &schema.Schema{
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "On create, wait until the member has an IP assignment, for as long as the create timeout allows. Use it when something else needs the member's managed address.",
+		}
 		start["network_id"] = &schema.Schema{
-			Type:        schema.TypeString,
-			Required:    true,
-			ForceNew:    true,
-			Description: "ID of the network this member belongs to.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validNetworkID,
+			Description:      "ID of the network this member belongs to.",
 		}
 		start["member_id"] = &schema.Schema{
-			Type:        schema.TypeString,
-			Required:    true,
-			Description: "ID of this member.",
+			Type:             schema.TypeString,
+			Required:         true,
+			ValidateDiagFunc: validMemberID,
+			Description:      "ID of this member.",
+		}
+		start["tags_by_name"] = &schema.Schema{
+			Type:          schema.TypeMap,
+			Optional:      true,
+			Elem:          &schema.Schema{Type: schema.TypeString},
+			ConflictsWith: []string{"tags"},
+			Description:   "Tags of this member by name, resolved against the tags defined by the network's flow rules into `tags`. Each value is a number, an enum of the tag, or flags of the tag joined with `|`. They are resolved when applying if the plan changes the rules or they don't define a name yet.",
+		}
+		start["capabilities_by_name"] = &schema.Schema{
+			Type:          schema.TypeSet,
+			Optional:      true,
+			Elem:          &schema.Schema{Type: schema.TypeString},
+			ConflictsWith: []string{"capabilities"},
+			Description:   "Capabilities of this member by name, resolved against the capabilities defined by the network's flow rules into `capabilities`. They are resolved when applying if the plan changes the rules or they don't define a name yet.",
+		}
+		start["deletion_protection"] = deletionProtectionSchema("member")
+		start["on_destroy"] = &schema.Schema{
+			Type:             schema.TypeString,
+			Optional:         true,
+			Default:          "delete",
+			ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(memberDestroyModes, false)),
+			Description:      "What destroying the member does: `delete` removes it from the network, `deauthorize` keeps its record but deauthorizes it, and `hide` also hides it, which only Central supports. Kept members keep their history and don't rejoin as new members.",
 		}
 	} else {
 		start["network_id"] = &schema.Schema{
@@ -174,13 +216,21 @@ func toMember(d *schema.ResourceData) *spec.Member {
 }
 
 func memberToTerraform(d *schema.ResourceData, m *spec.Member) diag.Diagnostics {
-	d.SetId(strings.Join([]string{*m.NetworkId, *m.NodeId}, "/"))
+	d.SetId(memberIdString(*m.NetworkId, *m.NodeId))
 
-	d.Set("name", *m.Name)
-	d.Set("description", *m.Description)
+	// name, description and hidden are nil on backends without them; leave
+	// them as configured.
+	if m.Name != nil {
+		d.Set("name", *m.Name)
+	}
+	if m.Description != nil {
+		d.Set("description", *m.Description)
+	}
+	if m.Hidden != nil {
+		d.Set("hidden", *m.Hidden)
+	}
 	d.Set("member_id", *m.NodeId)
 	d.Set("network_id", *m.NetworkId)
-	d.Set("hidden", *m.Hidden)
 	d.Set("authorized", *m.Config.Authorized)
 	d.Set("allow_ethernet_bridging", *m.Config.ActiveBridge)
 	d.Set("no_auto_assign_ips", *m.Config.NoAutoAssignIps)
@@ -200,6 +250,10 @@ func memberToTerraform(d *schema.ResourceData, m *spec.Member) diag.Diagnostics
 	d.Set("rfc4193", rfc4193Address(nwid, nodeID))
 	d.Set("sixplane", sixPlaneAddress(nwid, nodeID))
 
+	for key, value := range memberPresence(m, onlineThreshold(d)) {
+		d.Set(key, value)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,145 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"golang.org/x/sync/singleflight"
+)
+
+// memberCache serves member reads from one listing of their network, so a
+// refresh of hundreds of members costs one request per network instead of
+// one per member. Concurrent reads of a network that is not cached yet share
+// a single listing. Any write to a network's members drops its listing.
+//
+// The provider is configured anew for every terraform operation, so the
+// cache lives as long as a plan or an apply.
+type memberCache struct {
+	backend
+
+	group singleflight.Group
+
+	mutex       sync.Mutex
+	networks    map[string]*memberListing
+	generations map[string]uint64
+}
+
+type memberListing struct {
+	members []*spec.Member
+	byID    map[string]*spec.Member
+}
+
+var _ backend = (*memberCache)(nil)
+
+func newMemberCache(b backend) *memberCache {
+	return &memberCache{
+		backend:     b,
+		networks:    map[string]*memberListing{},
+		generations: map[string]uint64{},
+	}
+}
+
+func (c *memberCache) listing(ctx context.Context, networkID string) (*memberListing, error) {
+	c.mutex.Lock()
+	if l, ok := c.networks[networkID]; ok {
+		c.mutex.Unlock()
+		return l, nil
+	}
+	generation := c.generations[networkID]
+	c.mutex.Unlock()
+
+	// callers that arrive after an invalidation must not join a listing that
+	// started before it, so the generation is part of the key.
+	res, err, _ := c.group.Do(fmt.Sprintf("%s/%d", networkID, generation), func() (interface{}, error) {
+		logrus.Debugf("Listing members of network %s", networkID)
+
+		members, err := c.backend.GetMembers(ctx, networkID)
+		if err != nil {
+			return nil, err
+		}
+
+		l := &memberListing{members: members, byID: map[string]*spec.Member{}}
+		for _, member := range members {
+			if member.NodeId != nil {
+				l.byID[*member.NodeId] = member
+			}
+		}
+
+		c.mutex.Lock()
+		if c.generations[networkID] == generation {
+			c.networks[networkID] = l
+		}
+		c.mutex.Unlock()
+
+		return l, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*memberListing), nil
+}
+
+func (c *memberCache) invalidate(networkID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.networks, networkID)
+	c.generations[networkID]++
+}
+
+func (c *memberCache) GetMembers(ctx context.Context, networkID string) ([]*spec.Member, error) {
+	l, err := c.listing(ctx, networkID)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.members, nil
+}
+
+func (c *memberCache) GetMember(ctx context.Context, networkID, memberID string) (*spec.Member, error) {
+	l, err := c.listing(ctx, networkID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member, ok := l.byID[memberID]; ok {
+		return member, nil
+	}
+
+	// not listed; let the controller have the final word.
+	return c.backend.GetMember(ctx, networkID, memberID)
+}
+
+func (c *memberCache) CreateAuthorizedMember(ctx context.Context, networkID, memberID, name string) (*spec.Member, error) {
+	defer c.invalidate(networkID)
+	return c.backend.CreateAuthorizedMember(ctx, networkID, memberID, name)
+}
+
+func (c *memberCache) UpdateMember(ctx context.Context, networkID, memberID string, m *spec.Member) (*spec.Member, error) {
+	defer c.invalidate(networkID)
+	return c.backend.UpdateMember(ctx, networkID, memberID, m)
+}
+
+func (c *memberCache) DeleteMember(ctx context.Context, networkID, memberID string) error {
+	defer c.invalidate(networkID)
+	return c.backend.DeleteMember(ctx, networkID, memberID)
+}
+
+func (c *memberCache) DeleteNetwork(ctx context.Context, networkID string) error {
+	defer c.invalidate(networkID)
+	return c.backend.DeleteNetwork(ctx, networkID)
+}
+
+// freshMember reads a member past the cache, for polling a member until it
+// changes.
+func freshMember(ctx context.Context, c backend, networkID, memberID string) (*spec.Member, error) {
+	if cache, ok := underlyingBackend(c).(*memberCache); ok {
+		return cache.backend.GetMember(ctx, networkID, memberID)
+	}
+
+	return c.GetMember(ctx, networkID, memberID)
+}
@@ -0,0 +1,177 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
+)
+
+// testMemberCache returns a member cache in front of a fake with a network
+// of count members, and the IDs of the network and its members.
+func testMemberCache(tb testing.TB, count int) (*fakecentral.Server, *memberCache, string, []string) {
+	srv := fakecentral.New(testAccToken)
+	tb.Cleanup(srv.Close)
+
+	c, err := newCentralBackend(srv.URL(), srv.Token, http.DefaultTransport)
+	assert.NoError(tb, err)
+
+	n, err := c.NewNetwork(context.Background(), "cache", &spec.Network{})
+	assert.NoError(tb, err)
+
+	ids := []string{}
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("a1b2c3%04x", i)
+		srv.SetMember(*n.Id, id, &spec.Member{Name: stringPtr(id)})
+		ids = append(ids, id)
+	}
+
+	return srv, newMemberCache(c), *n.Id, ids
+}
+
+func TestMemberCache_OneListing(t *testing.T) {
+	srv, c, nwid, ids := testMemberCache(t, 10)
+	before := srv.Requests()
+
+	for _, id := range ids {
+		member, err := c.GetMember(context.Background(), nwid, id)
+		assert.NoError(t, err)
+		assert.Equal(t, id, *member.Name)
+	}
+
+	members, err := c.GetMembers(context.Background(), nwid)
+	assert.NoError(t, err)
+	assert.Len(t, members, 10)
+
+	assert.Equal(t, 1, srv.Requests()-before)
+}
+
+func TestMemberCache_Coalesced(t *testing.T) {
+	srv, c, nwid, ids := testMemberCache(t, 20)
+	srv.SetLatency(20 * time.Millisecond)
+	before := srv.Requests()
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			member, err := c.GetMember(context.Background(), nwid, id)
+			assert.NoError(t, err)
+			assert.Equal(t, id, *member.Name)
+		}(id)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, srv.Requests()-before)
+}
+
+func TestMemberCache_Invalidation(t *testing.T) {
+	srv, c, nwid, ids := testMemberCache(t, 2)
+
+	_, err := c.GetMember(context.Background(), nwid, ids[0])
+	assert.NoError(t, err)
+
+	_, err = c.UpdateMember(context.Background(), nwid, ids[0], &spec.Member{Name: stringPtr("renamed")})
+	assert.NoError(t, err)
+
+	member, err := c.GetMember(context.Background(), nwid, ids[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed", *member.Name)
+
+	assert.NoError(t, c.DeleteMember(context.Background(), nwid, ids[1]))
+
+	members, err := c.GetMembers(context.Background(), nwid)
+	assert.NoError(t, err)
+	assert.Len(t, members, 1)
+
+	_, err = c.GetMember(context.Background(), nwid, ids[1])
+	assert.ErrorContains(t, err, "404")
+
+	// changes made behind our back are not seen until the next operation.
+	srv.SetMember(nwid, ids[0], &spec.Member{Name: stringPtr("elsewhere")})
+
+	member, err = c.GetMember(context.Background(), nwid, ids[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed", *member.Name)
+}
+
+func TestMemberCache_NotListed(t *testing.T) {
+	srv, c, nwid, _ := testMemberCache(t, 1)
+
+	_, err := c.GetMembers(context.Background(), nwid)
+	assert.NoError(t, err)
+
+	srv.SetMember(nwid, "ffffffffff", &spec.Member{Name: stringPtr("late")})
+
+	member, err := c.GetMember(context.Background(), nwid, "ffffffffff")
+	assert.NoError(t, err)
+	assert.Equal(t, "late", *member.Name)
+}
+
+func TestMemberCache_Errors(t *testing.T) {
+	srv, c, _, _ := testMemberCache(t, 1)
+
+	_, err := c.GetMember(context.Background(), "0123456789abcdef", "a1b2c30000")
+	assert.ErrorContains(t, err, "404")
+
+	// failed listings are not cached.
+	n, err := c.NewNetwork(context.Background(), "late", &spec.Network{})
+	assert.NoError(t, err)
+
+	srv.FailNext(1, http.StatusForbidden, "")
+	_, err = c.GetMembers(context.Background(), *n.Id)
+	assert.ErrorContains(t, err, "403")
+
+	members, err := c.GetMembers(context.Background(), *n.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+// BenchmarkMemberRead refreshes every member of a large network with
+// terraform's default parallelism of 10, with and without the cache.
+func BenchmarkMemberRead(b *testing.B) {
+	for _, cached := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cached=%v", cached), func(b *testing.B) {
+			srv, cache, nwid, ids := testMemberCache(b, 800)
+
+			var c backend = cache.backend
+			before := srv.Requests()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if cached {
+					c = newMemberCache(cache.backend)
+				}
+
+				work := make(chan string)
+				var wg sync.WaitGroup
+				for w := 0; w < 10; w++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for id := range work {
+							if _, err := c.GetMember(context.Background(), nwid, id); err != nil {
+								b.Error(err)
+							}
+						}
+					}()
+				}
+
+				for _, id := range ids {
+					work <- id
+				}
+				close(work)
+				wg.Wait()
+			}
+
+			b.ReportMetric(float64(srv.Requests()-before)/float64(b.N), "requests/op")
+		})
+	}
+}
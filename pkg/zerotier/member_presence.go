@@ -0,0 +1,105 @@
+package zerotier
+
+import (
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+// defaultOnlineThreshold is how recently a member must have been online to
+// count as online, unless online_threshold says otherwise.
+const defaultOnlineThreshold = "5m"
+
+// memberPresenceSchema is what central reports about a member's runtime
+// state. It is all computed, so refreshing it never plans a change.
+var memberPresenceSchema = map[string]*schema.Schema{
+	"online": {
+		Type:        schema.TypeBool,
+		Computed:    true,
+		Description: "Whether the member was online within `online_threshold`.",
+	},
+	"last_online": {
+		Type:        schema.TypeInt,
+		Computed:    true,
+		Description: "When the member was last online, in milliseconds since the epoch; 0 if never.",
+	},
+	"last_seen": {
+		Type:        schema.TypeInt,
+		Computed:    true,
+		Description: "When the member last checked in with the controller, in milliseconds since the epoch; 0 if never.",
+	},
+	"physical_address": {
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: "The public address the member last connected from.",
+	},
+	"client_version": {
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: "The ZeroTier version the member runs.",
+	},
+	"protocol_version": {
+		Type:        schema.TypeInt,
+		Computed:    true,
+		Description: "The ZeroTier protocol version the member speaks.",
+	},
+	"creation_time": {
+		Type:        schema.TypeInt,
+		Computed:    true,
+		Description: "When the member was created, in milliseconds since the epoch.",
+	},
+	"last_authorized_time": {
+		Type:        schema.TypeInt,
+		Computed:    true,
+		Description: "When the member was last authorized, in milliseconds since the epoch; 0 if never.",
+	},
+	"last_deauthorized_time": {
+		Type:        schema.TypeInt,
+		Computed:    true,
+		Description: "When the member was last deauthorized, in milliseconds since the epoch; 0 if never.",
+	},
+}
+
+func onlineThresholdSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:             schema.TypeString,
+		Optional:         true,
+		Default:          defaultOnlineThreshold,
+		ValidateDiagFunc: validDuration,
+		Description:      "How recently a member must have been online to count as `online`, as a duration such as `5m`.",
+	}
+}
+
+// onlineThreshold parses the online_threshold of d.
+func onlineThreshold(d *schema.ResourceData) time.Duration {
+	threshold, err := time.ParseDuration(d.Get("online_threshold").(string))
+	if err != nil {
+		// validation keeps this from happening, but imports skip defaults.
+		threshold, _ = time.ParseDuration(defaultOnlineThreshold)
+	}
+
+	return threshold
+}
+
+// memberPresence returns the attributes of memberPresenceSchema for m.
+func memberPresence(m *spec.Member, threshold time.Duration) map[string]interface{} {
+	config := m.Config
+	if config == nil {
+		config = &spec.MemberConfig{}
+	}
+
+	lastOnline := ptrInt64(m.LastOnline)
+
+	return map[string]interface{}{
+		"online":                 lastOnline > 0 && time.Since(time.UnixMilli(lastOnline)) <= threshold,
+		"last_online":            lastOnline,
+		"last_seen":              ptrInt64(m.LastSeen),
+		"physical_address":       ptrString(m.PhysicalAddress),
+		"client_version":         ptrString(m.ClientVersion),
+		"protocol_version":       ptrInt(m.ProtocolVersion),
+		"creation_time":          ptrInt64(config.CreationTime),
+		"last_authorized_time":   ptrInt64(config.LastAuthorizedTime),
+		"last_deauthorized_time": ptrInt64(config.LastDeauthorizedTime),
+	}
+}
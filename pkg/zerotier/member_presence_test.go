@@ -0,0 +1,126 @@
+package zerotier
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+func TestMemberPresence(t *testing.T) {
+	minuteAgo := time.Now().Add(-time.Minute).UnixMilli()
+
+	m := &spec.Member{
+		LastOnline:      &minuteAgo,
+		PhysicalAddress: stringPtr("203.0.113.7/9993"),
+		ClientVersion:   stringPtr("1.14.0"),
+		ProtocolVersion: intPtr(13),
+		Config: &spec.MemberConfig{
+			LastAuthorizedTime: &minuteAgo,
+		},
+	}
+
+	presence := memberPresence(m, 5*time.Minute)
+	assert.Equal(t, true, presence["online"])
+	assert.Equal(t, minuteAgo, presence["last_online"])
+	assert.Equal(t, "203.0.113.7/9993", presence["physical_address"])
+	assert.Equal(t, "1.14.0", presence["client_version"])
+	assert.Equal(t, 13, presence["protocol_version"])
+	assert.Equal(t, minuteAgo, presence["last_authorized_time"])
+	assert.Equal(t, int64(0), presence["last_deauthorized_time"])
+
+	assert.Equal(t, false, memberPresence(m, 30*time.Second)["online"])
+
+	// a member that never came online is never online, and a backend that
+	// reports nothing leaves everything empty.
+	never := memberPresence(&spec.Member{}, 100*365*24*time.Hour)
+	assert.Equal(t, false, never["online"])
+	assert.Equal(t, int64(0), never["last_online"])
+	assert.Equal(t, "", never["client_version"])
+	assert.Len(t, never, len(memberPresenceSchema))
+}
+
+func TestAccMember_presence(t *testing.T) {
+	srv := testAccCentral(t)
+
+	config := func(threshold string) string {
+		return testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_network" "test" {
+  name = "acc-member-presence"
+}
+
+resource "zerotier_member" "test" {
+  network_id       = zerotier_network.test.id
+  member_id        = "a1b2c3d4e5"
+  online_threshold = %q
+}
+
+data "zerotier_members" "test" {
+  depends_on       = [zerotier_member.test]
+  network_id       = zerotier_network.test.id
+  online_threshold = "1h"
+}
+`, threshold))
+	}
+
+	var nwid string
+
+	setPresence := func(ago time.Duration) {
+		lastOnline := time.Now().Add(-ago).UnixMilli()
+		srv.SetMember(nwid, "a1b2c3d4e5", &spec.Member{
+			LastOnline:      &lastOnline,
+			LastSeen:        &lastOnline,
+			PhysicalAddress: stringPtr("203.0.113.7/9993"),
+			ClientVersion:   stringPtr("1.14.0"),
+			ProtocolVersion: intPtr(13),
+		})
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: config("5m"),
+				Check: resource.ComposeTestCheckFunc(
+					func(s *terraform.State) error {
+						nwid = s.RootModule().Resources["zerotier_network.test"].Primary.ID
+						return nil
+					},
+					resource.TestCheckResourceAttr("zerotier_member.test", "online", "false"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "last_online", "0"),
+					resource.TestCheckResourceAttrSet("zerotier_member.test", "creation_time"),
+				),
+			},
+			{
+				// the member coming online changes nothing in the plan.
+				PreConfig: func() { setPresence(time.Minute) },
+				Config:    config("5m"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_member.test", "online", "true"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "physical_address", "203.0.113.7/9993"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "client_version", "1.14.0"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "protocol_version", "13"),
+					resource.TestCheckResourceAttr("data.zerotier_members.test", "members.0.online", "true"),
+					resource.TestCheckResourceAttr("data.zerotier_members.test", "members.0.client_version", "1.14.0"),
+				),
+			},
+			{
+				PreConfig: func() { setPresence(10 * time.Minute) },
+				Config:    config("5m"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_member.test", "online", "false"),
+					resource.TestCheckResourceAttr("data.zerotier_members.test", "members.0.online", "true"),
+				),
+			},
+			{
+				Config: config("15m"),
+				Check:  resource.TestCheckResourceAttr("zerotier_member.test", "online", "true"),
+			},
+		},
+	})
+}
@@ -0,0 +1,259 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/rules"
+)
+
+// memberRuleNames are the attributes naming tags and capabilities, and the
+// numeric attributes they are resolved to.
+var memberRuleNames = map[string]string{
+	"tags_by_name":         "tags",
+	"capabilities_by_name": "capabilities",
+}
+
+// resourceMemberCustomizeRuleNames plans tags and capabilities from
+// tags_by_name and capabilities_by_name, resolved against the network's flow
+// rules as they are now. When the network doesn't exist yet, the plan
+// changes its rules, or a name isn't defined by them, they are resolved when
+// applying instead.
+func resourceMemberCustomizeRuleNames(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	var resolve []string
+
+	for byName, numeric := range memberRuleNames {
+		switch {
+		case !d.NewValueKnown(byName):
+			if err := d.SetNewComputed(numeric); err != nil {
+				return err
+			}
+		case memberRuleNamesConfigured(d.Get, byName):
+			resolve = append(resolve, byName)
+		case d.GetRawConfig().GetAttr(numeric).IsNull() && d.HasChange(byName):
+			// the names were removed, and nothing else sets the numbers.
+			if err := d.SetNew(numeric, []interface{}{}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(resolve) == 0 {
+		return nil
+	}
+
+	nwid := d.Get("network_id").(string)
+
+	if !d.NewValueKnown("network_id") || networkRulesChanging(m, nwid) {
+		for _, byName := range resolve {
+			if err := d.SetNewComputed(memberRuleNames[byName]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	program, err := memberNetworkRules(ctx, m.(backend), nwid)
+	if err != nil {
+		return err
+	}
+
+	for _, byName := range resolve {
+		value, err := resolveMemberRuleNames(program, d.Get, byName)
+		if err != nil {
+			// the rules may be changed by a network this plan can't see;
+			// applying reports the name if they still don't define it.
+			if err := d.SetNewComputed(memberRuleNames[byName]); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := d.SetNew(memberRuleNames[byName], value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMemberRuleNames resolves tags_by_name and capabilities_by_name into
+// member, for members whose plan couldn't resolve them.
+func applyMemberRuleNames(ctx context.Context, c backend, d *schema.ResourceData, member *spec.Member) error {
+	if !memberRuleNamesConfigured(d.Get, "tags_by_name") && !memberRuleNamesConfigured(d.Get, "capabilities_by_name") {
+		return nil
+	}
+
+	program, err := memberNetworkRules(ctx, c, *member.NetworkId)
+	if err != nil {
+		return err
+	}
+
+	if memberRuleNamesConfigured(d.Get, "tags_by_name") {
+		tags, err := resolveMemberRuleNames(program, d.Get, "tags_by_name")
+		if err != nil {
+			return err
+		}
+
+		member.Config.Tags = fetchTags(tags)
+	}
+
+	if memberRuleNamesConfigured(d.Get, "capabilities_by_name") {
+		capabilities, err := resolveMemberRuleNames(program, d.Get, "capabilities_by_name")
+		if err != nil {
+			return err
+		}
+
+		member.Config.Capabilities = toIntList(capabilities).(*[]int)
+	}
+
+	return nil
+}
+
+func memberRuleNamesConfigured(get func(string) interface{}, byName string) bool {
+	switch v := get(byName).(type) {
+	case map[string]interface{}:
+		return len(v) > 0
+	case *schema.Set:
+		return v.Len() > 0
+	}
+
+	return false
+}
+
+// memberNetwork reads the network nwid, from the cache when the provider has
+// one.
+func memberNetwork(ctx context.Context, c backend, nwid string) (*spec.Network, error) {
+	if cache, ok := c.(*networkCache); ok {
+		return cache.network(ctx, nwid)
+	}
+
+	return c.GetNetwork(ctx, nwid)
+}
+
+// memberNetworkRules returns the compiled flow rules of the network nwid,
+// from the cache when the provider has one.
+func memberNetworkRules(ctx context.Context, c backend, nwid string) (*rules.Program, error) {
+	if cache, ok := c.(*networkCache); ok {
+		return cache.flowRules(ctx, nwid)
+	}
+
+	return compileNetworkRules(ctx, c, nwid)
+}
+
+// networkRulesChanging reports whether the plan changes the flow rules of the
+// network nwid.
+func networkRulesChanging(m interface{}, nwid string) bool {
+	cache, ok := m.(*networkCache)
+	return ok && cache.rulesChanging(nwid)
+}
+
+// compileNetworkRules reads and compiles the flow rules of the network nwid.
+func compileNetworkRules(ctx context.Context, c backend, nwid string) (*rules.Program, error) {
+	n, err := memberNetwork(ctx, c, nwid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the flow rules of network %s: %w", nwid, err)
+	}
+
+	program, err := rules.Compile(ptrString(n.RulesSource))
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile the flow rules of network %s: %w", nwid, err)
+	}
+
+	return program, nil
+}
+
+// resolveMemberRuleNames returns the value of the numeric attribute byName
+// resolves to, as it is set in terraform.
+func resolveMemberRuleNames(program *rules.Program, get func(string) interface{}, byName string) ([]interface{}, error) {
+	res := []interface{}{}
+
+	switch byName {
+	case "tags_by_name":
+		values := get(byName).(map[string]interface{})
+
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			value := values[name]
+			tag, ok := program.Tags[name]
+			if !ok {
+				return nil, fmt.Errorf("tags_by_name: unknown tag %q; the network's flow rules define %s", name, definedNames(program.Tags))
+			}
+
+			v, err := resolveTagValue(name, tag, value.(string))
+			if err != nil {
+				return nil, fmt.Errorf("tags_by_name: %w", err)
+			}
+
+			res = append(res, []interface{}{int(tag.ID), int(v)})
+		}
+	case "capabilities_by_name":
+		for _, name := range get(byName).(*schema.Set).List() {
+			capability, ok := program.Capabilities[name.(string)]
+			if !ok {
+				return nil, fmt.Errorf("capabilities_by_name: unknown capability %q; the network's flow rules define %s", name, definedNames(program.Capabilities))
+			}
+
+			res = append(res, int(capability.ID))
+		}
+	}
+
+	return res, nil
+}
+
+// resolveTagValue resolves the value of a member's tag: a number, an enum of
+// the tag, or flags of the tag joined with |.
+func resolveTagValue(name string, tag *rules.Tag, value string) (uint32, error) {
+	var res uint32
+
+	for _, part := range strings.Split(value, "|") {
+		part = strings.TrimSpace(part)
+
+		if n, err := strconv.ParseUint(part, 0, 32); err == nil {
+			res |= uint32(n)
+		} else if enum, ok := tag.Enums[part]; ok {
+			res |= enum
+		} else if bit, ok := tag.Flags[part]; ok {
+			res |= 1 << bit
+		} else {
+			values := map[string]bool{}
+			for v := range tag.Enums {
+				values[v] = true
+			}
+			for v := range tag.Flags {
+				values[v] = true
+			}
+
+			return 0, fmt.Errorf("unknown value %q for tag %q; expected a number or one of %s", part, name, definedNames(values))
+		}
+	}
+
+	return res, nil
+}
+
+// definedNames lists the keys of a map of definitions for an error message.
+func definedNames[T any](defined map[string]T) string {
+	if len(defined) == 0 {
+		return "none"
+	}
+
+	names := make([]string, 0, len(defined))
+	for name := range defined {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,326 @@
+package zerotier
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/rules"
+)
+
+const testMemberRules = `
+tag department id 1000 enum 100 sales enum 200 engineering;
+tag roles id 1001 flag 0 admin flag 1 ops flag 4 audit;
+cap superuser id 1
+  accept;
+;
+cap readonly id 2;
+;
+accept;
+`
+
+func TestResolveTagValue(t *testing.T) {
+	program, err := rules.Compile(testMemberRules)
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		tag      string
+		value    string
+		expected uint32
+		err      string
+	}{
+		"number": {
+			tag:      "department",
+			value:    "42",
+			expected: 42,
+		},
+		"hex number": {
+			tag:      "roles",
+			value:    "0x11",
+			expected: 17,
+		},
+		"enum": {
+			tag:      "department",
+			value:    "engineering",
+			expected: 200,
+		},
+		"flag": {
+			tag:      "roles",
+			value:    "ops",
+			expected: 2,
+		},
+		"flags": {
+			tag:      "roles",
+			value:    "admin | audit",
+			expected: 17,
+		},
+		"unknown enum": {
+			tag:   "department",
+			value: "marketing",
+			err:   `unknown value "marketing" for tag "department"; expected a number or one of engineering, sales`,
+		},
+		"unknown flag": {
+			tag:   "roles",
+			value: "admin|root",
+			err:   `unknown value "root" for tag "roles"; expected a number or one of admin, audit, ops`,
+		},
+		"empty": {
+			tag:   "department",
+			value: "",
+			err:   `unknown value "" for tag "department"; expected a number or one of engineering, sales`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual, err := resolveTagValue(test.tag, program.Tags[test.tag], test.value)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestResolveMemberRuleNames(t *testing.T) {
+	program, err := rules.Compile(testMemberRules)
+	assert.NoError(t, err)
+
+	resolve := func(raw map[string]interface{}, byName string) ([]interface{}, error) {
+		d := schema.TestResourceDataRaw(t, resourceMember().Schema, raw)
+		return resolveMemberRuleNames(program, d.Get, byName)
+	}
+
+	tags, err := resolve(map[string]interface{}{
+		"tags_by_name": map[string]interface{}{"department": "sales", "roles": "admin|ops"},
+	}, "tags_by_name")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{1000, 100}, []interface{}{1001, 3}}, tags)
+
+	capabilities, err := resolve(map[string]interface{}{
+		"capabilities_by_name": []interface{}{"superuser", "readonly"},
+	}, "capabilities_by_name")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []interface{}{1, 2}, capabilities)
+
+	_, err = resolve(map[string]interface{}{
+		"tags_by_name": map[string]interface{}{"department": "sales", "location": "berlin"},
+	}, "tags_by_name")
+	assert.EqualError(t, err, `tags_by_name: unknown tag "location"; the network's flow rules define department, roles`)
+
+	_, err = resolve(map[string]interface{}{
+		"tags_by_name": map[string]interface{}{"department": "marketing"},
+	}, "tags_by_name")
+	assert.EqualError(t, err, `tags_by_name: unknown value "marketing" for tag "department"; expected a number or one of engineering, sales`)
+
+	_, err = resolve(map[string]interface{}{
+		"capabilities_by_name": []interface{}{"root"},
+	}, "capabilities_by_name")
+	assert.EqualError(t, err, `capabilities_by_name: unknown capability "root"; the network's flow rules define readonly, superuser`)
+
+	empty, err := rules.Compile("accept;")
+	assert.NoError(t, err)
+
+	d := schema.TestResourceDataRaw(t, resourceMember().Schema, map[string]interface{}{
+		"capabilities_by_name": []interface{}{"root"},
+	})
+	_, err = resolveMemberRuleNames(empty, d.Get, "capabilities_by_name")
+	assert.EqualError(t, err, `capabilities_by_name: unknown capability "root"; the network's flow rules define none`)
+}
+
+// testAccCheckMemberTags checks the tags and capabilities stored by Central
+// for the member.
+func testAccCheckMemberTags(srv *fakecentral.Server, name string, tags [][]int, capabilities []int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs := s.RootModule().Resources[name]
+		m := srv.Member(rs.Primary.Attributes["network_id"], rs.Primary.Attributes["member_id"])
+		if m == nil {
+			return fmt.Errorf("member %s does not exist", rs.Primary.ID)
+		}
+
+		actualTags := [][]int{}
+		for _, tag := range *m.Config.Tags {
+			actualTags = append(actualTags, []int{int(tag[0].(float64)), int(tag[1].(float64))})
+		}
+		sort.Slice(actualTags, func(i, j int) bool { return actualTags[i][0] < actualTags[j][0] })
+
+		actualCapabilities := append([]int{}, *m.Config.Capabilities...)
+		sort.Ints(actualCapabilities)
+
+		if fmt.Sprint(actualTags) != fmt.Sprint(tags) {
+			return fmt.Errorf("expected tags %v, got %v", tags, actualTags)
+		}
+		if fmt.Sprint(actualCapabilities) != fmt.Sprint(capabilities) {
+			return fmt.Errorf("expected capabilities %v, got %v", capabilities, actualCapabilities)
+		}
+
+		return nil
+	}
+}
+
+func TestAccMember_ruleNames(t *testing.T) {
+	srv := testAccCentral(t)
+
+	network := fmt.Sprintf(`
+resource "zerotier_network" "test" {
+  name       = "acc-member-rule-names"
+  flow_rules = <<-EOT
+%s
+  EOT
+}
+`, testMemberRules)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				// the network doesn't exist yet, so the names are resolved
+				// when applying.
+				Config: testAccConfig(srv, network+`
+resource "zerotier_member" "test" {
+  network_id           = zerotier_network.test.id
+  member_id            = "a1b2c3d4e5"
+  tags_by_name         = { department = "sales", roles = "admin|ops" }
+  capabilities_by_name = ["superuser"]
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckMemberTags(srv, "zerotier_member.test", [][]int{{1000, 100}, {1001, 3}}, []int{1}),
+					resource.TestCheckResourceAttr("zerotier_member.test", "tags.#", "2"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "capabilities.#", "1"),
+					resource.TestCheckTypeSetElemAttr("zerotier_member.test", "capabilities.*", "1"),
+				),
+			},
+			{
+				Config: testAccConfig(srv, network+`
+resource "zerotier_member" "test" {
+  network_id           = zerotier_network.test.id
+  member_id            = "a1b2c3d4e5"
+  tags_by_name         = { department = "engineering" }
+  capabilities_by_name = ["superuser", "readonly"]
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckMemberTags(srv, "zerotier_member.test", [][]int{{1000, 200}}, []int{1, 2}),
+					resource.TestCheckResourceAttr("zerotier_member.test", "capabilities.#", "2"),
+				),
+			},
+			{
+				// names the rules don't define are reported when applying.
+				Config: testAccConfig(srv, network+`
+resource "zerotier_member" "test" {
+  network_id   = zerotier_network.test.id
+  member_id    = "a1b2c3d4e5"
+  tags_by_name = { department = "marketing" }
+}
+`),
+				ExpectError: regexp.MustCompile(`unknown value "marketing" for tag "department"`),
+			},
+			{
+				Config: testAccConfig(srv, network+`
+resource "zerotier_member" "test" {
+  network_id           = zerotier_network.test.id
+  member_id            = "a1b2c3d4e5"
+  capabilities_by_name = ["root"]
+}
+`),
+				ExpectError: regexp.MustCompile(`unknown capability "root"; the network's flow rules define readonly, superuser`),
+			},
+			{
+				// without the names, the numbers are cleared.
+				Config: testAccConfig(srv, network+`
+resource "zerotier_member" "test" {
+  network_id = zerotier_network.test.id
+  member_id  = "a1b2c3d4e5"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckMemberTags(srv, "zerotier_member.test", [][]int{}, []int{}),
+					resource.TestCheckResourceAttr("zerotier_member.test", "capabilities.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccMember_ruleNamesChangingRules(t *testing.T) {
+	srv := testAccCentral(t)
+
+	config := func(rules, tags string) string {
+		return testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_network" "test" {
+  name       = "acc-member-rule-names-changing"
+  flow_rules = <<-EOT
+%s
+  EOT
+}
+
+resource "zerotier_member" "test" {
+  network_id   = zerotier_network.test.id
+  member_id    = "a1b2c3d4e5"
+  tags_by_name = %s
+}
+`, rules, tags))
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: config(testMemberRules, `{ department = "sales" }`),
+				Check:  testAccCheckMemberTags(srv, "zerotier_member.test", [][]int{{1000, 100}}, []int{}),
+			},
+			{
+				// the tag is added in the same apply that uses it.
+				Config: config(`
+tag department id 1000 enum 100 sales enum 200 engineering;
+tag location id 1002 enum 1 berlin;
+accept;
+`, `{ department = "sales", location = "berlin" }`),
+				Check: testAccCheckMemberTags(srv, "zerotier_member.test", [][]int{{1000, 100}, {1002, 1}}, []int{}),
+			},
+			{
+				// the names are unchanged, but one of them is renumbered.
+				Config: config(`
+tag department id 1005 enum 100 sales enum 200 engineering;
+tag location id 1002 enum 1 berlin;
+accept;
+`, `{ department = "sales", location = "berlin" }`),
+				Check: testAccCheckMemberTags(srv, "zerotier_member.test", [][]int{{1002, 1}, {1005, 100}}, []int{}),
+			},
+		},
+	})
+}
+
+func TestAccMember_ruleNamesConflict(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_member" "test" {
+  network_id   = "0123456789abcdef"
+  member_id    = "a1b2c3d4e5"
+  tags         = [[1000, 100]]
+  tags_by_name = { department = "sales" }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`"tags_by_name": conflicts with tags`),
+			},
+		},
+	})
+}
@@ -0,0 +1,98 @@
+package zerotier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/sirupsen/logrus"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+const defaultMemberCreateTimeout = 10 * time.Minute
+
+// memberPollInterval is how often a member being waited for is read. It is
+// a variable so tests can wait less.
+var memberPollInterval = 5 * time.Second
+
+// memberCondition is something create can wait for a member to be.
+type memberCondition struct {
+	attr string
+	desc string
+	met  func(m *spec.Member, threshold time.Duration) bool
+}
+
+var memberConditions = []memberCondition{
+	{
+		attr: "wait_for_online",
+		desc: "come online",
+		met: func(m *spec.Member, threshold time.Duration) bool {
+			return memberPresence(m, threshold)["online"].(bool)
+		},
+	},
+	{
+		attr: "wait_for_ip_assignment",
+		desc: "be assigned an IP address",
+		met: func(m *spec.Member, threshold time.Duration) bool {
+			return m.Config != nil && m.Config.IpAssignments != nil && len(*m.Config.IpAssignments) > 0
+		},
+	},
+}
+
+// waitForMember polls the member until it meets the conditions d asks for.
+// ctx carries the create timeout, which also covers creating the member. It
+// returns the last member read, which is member if there was nothing to wait
+// for.
+func waitForMember(ctx context.Context, c backend, d *schema.ResourceData, member *spec.Member) (*spec.Member, diag.Diagnostics) {
+	conditions := []memberCondition{}
+	for _, cond := range memberConditions {
+		if d.Get(cond.attr).(bool) {
+			conditions = append(conditions, cond)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return member, nil
+	}
+
+	nwid, nodeID := *member.NetworkId, *member.NodeId
+	threshold := onlineThreshold(d)
+
+	for {
+		unmet := []string{}
+		for _, cond := range conditions {
+			if !cond.met(member, threshold) {
+				unmet = append(unmet, cond.desc)
+			}
+		}
+
+		if len(unmet) == 0 {
+			return member, nil
+		}
+
+		logrus.Debugf("Waiting for ZeroTier Member %s of network %s to %s", nodeID, nwid, strings.Join(unmet, " and "))
+
+		err := sleepContext(ctx, memberPollInterval)
+		if err == nil {
+			member, err = freshMember(ctx, c, nwid, nodeID)
+		}
+
+		// the deadline can pass while sleeping or while reading the member.
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, diag.Diagnostics{{
+				Severity: diag.Error,
+				Summary:  "Timed out waiting for ZeroTier Member",
+				Detail: fmt.Sprintf("Member %s of network %s did not %s within the create timeout of %s. Check that the device has joined the network, or raise the create timeout. The member was created and is marked as tainted, so it will be replaced on the next apply.",
+					nodeID, nwid, strings.Join(unmet, " and "), d.Timeout(schema.TimeoutCreate)),
+			}}
+		}
+
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+	}
+}
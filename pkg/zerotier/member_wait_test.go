@@ -0,0 +1,160 @@
+package zerotier
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
+)
+
+// testAccShortPolls makes waiting for members poll often for the rest of the
+// test.
+func testAccShortPolls(t *testing.T) {
+	interval := memberPollInterval
+	memberPollInterval = 20 * time.Millisecond
+	t.Cleanup(func() { memberPollInterval = interval })
+}
+
+// testAccJoin plays the device and the controller: once the provider has
+// created and configured the member memberID, it applies join to it after a
+// delay, as if the device had joined.
+func testAccJoin(t *testing.T, srv *fakecentral.Server, memberID string, join func(nwid string)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		for ctx.Err() == nil {
+			for _, nwid := range srv.NetworkIDs() {
+				// revision 2 is the update after the create; joining before it
+				// would be overwritten.
+				if m := srv.Member(nwid, memberID); m != nil && *m.Config.Revision >= 2 {
+					time.Sleep(100 * time.Millisecond)
+					join(nwid)
+					return
+				}
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+}
+
+func TestWaitForMember_Conditions(t *testing.T) {
+	now := time.Now().UnixMilli()
+
+	online, ip := memberConditions[0], memberConditions[1]
+
+	assert.False(t, online.met(&spec.Member{}, time.Minute))
+	assert.True(t, online.met(&spec.Member{LastOnline: &now}, time.Minute))
+
+	assert.False(t, ip.met(&spec.Member{}, time.Minute))
+	assert.False(t, ip.met(&spec.Member{Config: &spec.MemberConfig{IpAssignments: &[]string{}}}, time.Minute))
+	assert.True(t, ip.met(&spec.Member{Config: &spec.MemberConfig{IpAssignments: &[]string{"10.0.0.10"}}}, time.Minute))
+}
+
+func TestWaitForMember_DeadlineDuringRead(t *testing.T) {
+	srv, c, nwid, ids := testMemberCache(t, 1)
+	testAccShortPolls(t)
+
+	member, err := c.GetMember(context.Background(), nwid, ids[0])
+	assert.NoError(t, err)
+
+	// the deadline passes while the member is being read, not while sleeping.
+	srv.SetLatency(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	d := schema.TestResourceDataRaw(t, resourceMember().Schema, map[string]interface{}{
+		"wait_for_online": true,
+	})
+
+	_, diags := waitForMember(ctx, c, d, member)
+	assert.Len(t, diags, 1)
+	assert.Equal(t, "Timed out waiting for ZeroTier Member", diags[0].Summary)
+}
+
+func TestAccMember_waitForIPAssignment(t *testing.T) {
+	srv := testAccCentral(t)
+	testAccShortPolls(t)
+
+	testAccJoin(t, srv, "a1b2c3d4e5", func(nwid string) {
+		lastOnline := time.Now().UnixMilli()
+		srv.SetMember(nwid, "a1b2c3d4e5", &spec.Member{
+			LastOnline: &lastOnline,
+			Config:     &spec.MemberConfig{IpAssignments: &[]string{"10.0.0.10"}},
+		})
+	})
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-member-wait"
+
+  subnet {
+    cidr     = "10.0.0.0/24"
+    reserved = 9
+  }
+}
+
+resource "zerotier_member" "test" {
+  network_id             = zerotier_network.test.id
+  member_id              = "a1b2c3d4e5"
+  wait_for_online        = true
+  wait_for_ip_assignment = true
+}
+
+# what needs the address gets it at apply time, not unknown or empty.
+resource "terraform_data" "address" {
+  input = one(zerotier_member.test.ipv4_assignments)
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_member.test", "online", "true"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "ip_assignments.#", "1"),
+					resource.TestCheckResourceAttr("terraform_data.address", "output", "10.0.0.10"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccMember_waitTimeout(t *testing.T) {
+	srv := testAccCentral(t)
+	testAccShortPolls(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-member-wait"
+}
+
+resource "zerotier_member" "test" {
+  network_id      = zerotier_network.test.id
+  member_id       = "a1b2c3d4e5"
+  wait_for_online = true
+
+  timeouts {
+    create = "1s"
+  }
+}
+`),
+				ExpectError: regexp.MustCompile(`Member a1b2c3d4e5 of network [0-9a-f]{16} did not come online\s+within\s+the\s+create\s+timeout\s+of\s+1s`),
+			},
+		},
+	})
+}
@@ -1,13 +1,19 @@
 package zerotier
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/sirupsen/logrus"
 	"github.com/zerotier/go-ztcentral/pkg/spec"
 )
 
-// NetworkSchema is our terraform network resource's schema.
+// NetworkSchema is the base of the network resource's schema. The network
+// data sources compute it.
 var NetworkSchema = map[string]*schema.Schema{
 	"id": {
 		Type:        schema.TypeString,
@@ -44,6 +50,13 @@ var NetworkSchema = map[string]*schema.Schema{
 		Default:     32,
 		Description: "Maximum number of recipients per multicast or broadcast. Warning - Setting this to 0 will disable IPv4 communication on your network!",
 	},
+	"mtu": {
+		Type:             schema.TypeInt,
+		Optional:         true,
+		Default:          2800,
+		ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1280, 10000)),
+		Description:      "MTU to set on the virtual network adapter of members, between 1280 and 10000.",
+	},
 	"private": {
 		Type:        schema.TypeBool,
 		Optional:    true,
@@ -73,25 +86,30 @@ var NetworkSchema = map[string]*schema.Schema{
 	"dns": {
 		Type:     schema.TypeSet,
 		Optional: true,
-		Computed: true,
+		MaxItems: 1,
+		Set:      dnsHash,
 		Elem: &schema.Resource{
 			Schema: map[string]*schema.Schema{
 				"domain": {
-					Type:        schema.TypeString,
-					Required:    true,
-					Description: "Domain suffix for DNS searches",
+					Type:             schema.TypeString,
+					Required:         true,
+					ValidateDiagFunc: validDomain,
+					Description:      "Domain suffix for DNS searches",
 				},
 				"servers": {
 					Type: schema.TypeList,
 					Elem: &schema.Schema{
-						Type: schema.TypeString,
+						Type:             schema.TypeString,
+						ValidateDiagFunc: validIP,
 					},
 					Required:    true,
-					Description: "Nameservers to send DNS requests to",
+					MinItems:    1,
+					MaxItems:    maxDNSServers,
+					Description: fmt.Sprintf("Nameservers to send DNS requests to, in order of preference; at most %d.", maxDNSServers),
 				},
 			},
 		},
-		Description: "DNS settings for network members",
+		Description: "DNS settings for network members. Without it, the network has no DNS settings.",
 	},
 	"assign_ipv4": {
 		Type:     schema.TypeSet,
@@ -137,6 +155,12 @@ var NetworkSchema = map[string]*schema.Schema{
 		},
 		Description: "IPv6 Assignment RuleSets",
 	},
+	"subnet": {
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem:        subnetResource(),
+		Description: "Subnets of the network, each shorthand for a route to it and an assignment pool of its addresses. They can be combined with `route` and `assignment_pool` blocks, which don't list them.",
+	},
 	"assignment_pool": {
 		Type:     schema.TypeSet,
 		Optional: true,
@@ -154,14 +178,109 @@ var NetworkSchema = map[string]*schema.Schema{
 					Description: "The last address in the assignment rule. This must be the highest number in the pool. end must also be accompanied by start.",
 				},
 			},
-			Description: "Rules regarding IPv4 and IPv6 assignments",
+			Description: "Rules regarding IPv4 and IPv6 assignments. Each pool must be inside the target of a route.",
+		},
+	},
+	"sso": {
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"enabled": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     true,
+					Description: "Require members to authenticate with SSO. Members with `sso_exempt` set don't need to.",
+				},
+				"mode": {
+					Type:             schema.TypeString,
+					Optional:         true,
+					Default:          "default",
+					ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(ssoModes, false)),
+					Description:      "Who may join: `default` lets anyone who can sign in with the issuer, `email` and `group` only the emails or groups in `allowed_members`.",
+				},
+				"issuer": {
+					Type:             schema.TypeString,
+					Optional:         true,
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithHTTPS),
+					Description:      "URL of the OIDC issuer. It must be configured in the organization.",
+				},
+				"client_id": {
+					Type:             schema.TypeString,
+					Optional:         true,
+					ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsNotWhiteSpace),
+					Description:      "The OIDC client ID. It must be configured in the organization.",
+				},
+				"authorization_endpoint": {
+					Type:             schema.TypeString,
+					Optional:         true,
+					Computed:         true,
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithHTTPS),
+					Description:      "URL members are sent to to authenticate. Central discovers it from the issuer if it is not set.",
+				},
+				"allowed_members": {
+					Type:        schema.TypeSet,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+					Description: "The email addresses or groups, depending on `mode`, that may join the network.",
+				},
+			},
 		},
+		Description: "Single sign-on settings, only offered by ZeroTier Central. SSO is disabled when this block is removed.",
 	},
 	"flow_rules": {
-		Type:        schema.TypeString,
-		Optional:    true,
-		Default:     "accept;",
-		Description: "The layer 2 flow rules to apply to packets traveling across this network. Please see https://www.zerotier.com/manual/#3_4_1 for more information.",
+		Type:             schema.TypeString,
+		Optional:         true,
+		Computed:         true,
+		ValidateDiagFunc: validFlowRules,
+		DiffSuppressFunc: suppressEquivalentFlowRules,
+		Description:      "The layer 2 flow rules to apply to packets traveling across this network, `accept;` if not set. They are checked for mistakes when planning, and changes to only whitespace or comments are ignored. Please see https://www.zerotier.com/manual/#3_4_1 for more information.",
+	},
+	"rule": {
+		Type:          schema.TypeList,
+		Optional:      true,
+		Elem:          flowRuleResource(),
+		ConflictsWith: []string{"flow_rules"},
+		Description:   "Flow rules as structured blocks, applied in order, instead of `flow_rules`. They are rendered to `flow_rules`.",
+	},
+	"tag": {
+		Type:          schema.TypeList,
+		Optional:      true,
+		Elem:          flowRuleTagResource(),
+		ConflictsWith: []string{"flow_rules"},
+		Description:   "Tag definitions to go with `rule` blocks. They are rendered to `flow_rules`.",
+	},
+	"capability": {
+		Type:          schema.TypeList,
+		Optional:      true,
+		Elem:          flowRuleCapabilityResource(),
+		ConflictsWith: []string{"flow_rules"},
+		Description:   "Capability definitions to go with `rule` blocks. They are rendered to `flow_rules`.",
+	},
+	"tags_by_name": {
+		Type:        schema.TypeMap,
+		Computed:    true,
+		Elem:        &schema.Schema{Type: schema.TypeInt},
+		Description: "The IDs of the tags defined by the flow rules, by name.",
+	},
+	"capabilities_by_name": {
+		Type:        schema.TypeMap,
+		Computed:    true,
+		Elem:        &schema.Schema{Type: schema.TypeInt},
+		Description: "The IDs of the capabilities defined by the flow rules, by name.",
+	},
+	"tag_enums": {
+		Type:        schema.TypeMap,
+		Computed:    true,
+		Elem:        &schema.Schema{Type: schema.TypeInt},
+		Description: "The values of the enums of the tags defined by the flow rules, by `<tag>.<enum>`.",
+	},
+	"tag_flags": {
+		Type:        schema.TypeMap,
+		Computed:    true,
+		Elem:        &schema.Schema{Type: schema.TypeInt},
+		Description: "The bits of the flags of the tags defined by the flow rules, by `<tag>.<flag>`.",
 	},
 }
 
@@ -176,6 +295,14 @@ func toNetwork(d *schema.ResourceData) (*spec.Network, diag.Diagnostics) {
 		return nil, err
 	}
 
+	subnetRoutes, subnetPools, serr := mkSubnets(d.Get("subnet"))
+	if serr != nil {
+		return nil, diag.FromErr(serr)
+	}
+
+	allPools := withSubnetPools(*assignmentPools.(*[]spec.IPRange), subnetPools)
+	allRoutes := withSubnetRoutes(*routes.(*[]spec.Route), subnetRoutes)
+
 	v4assign, err := mkipv4assign(d.Get("assign_ipv4"))
 	if err != nil {
 		return nil, err
@@ -191,20 +318,27 @@ func toNetwork(d *schema.ResourceData) (*spec.Network, diag.Diagnostics) {
 		return nil, err
 	}
 
+	rulesSource, rerr := networkRulesSource(d)
+	if rerr != nil {
+		return nil, diag.FromErr(rerr)
+	}
+
 	network := &spec.Network{
 		Id:          stringPtr(d.Get("id").(string)),
-		RulesSource: stringPtr(d.Get("flow_rules").(string)),
+		RulesSource: stringPtr(rulesSource),
 		Description: stringPtr(d.Get("description").(string)),
 		Config: &spec.NetworkConfig{
 			Name:              stringPtr(d.Get("name").(string)),
-			IpAssignmentPools: (assignmentPools).(*[]spec.IPRange),
-			Routes:            (routes).(*[]spec.Route),
+			IpAssignmentPools: &allPools,
+			Routes:            &allRoutes,
 			V4AssignMode:      (v4assign).(*spec.IPV4AssignMode),
 			V6AssignMode:      (v6assign).(*spec.IPV6AssignMode),
 			EnableBroadcast:   boolPtr(d.Get("enable_broadcast").(bool)),
 			MulticastLimit:    intPtr(d.Get("multicast_limit").(int)),
+			Mtu:               intPtr(d.Get("mtu").(int)),
 			Private:           boolPtr(d.Get("private").(bool)),
 			Dns:               dns.(*spec.DNS),
+			SsoConfig:         mkSSO(d.Get("sso")),
 		},
 	}
 
@@ -213,20 +347,95 @@ func toNetwork(d *schema.ResourceData) (*spec.Network, diag.Diagnostics) {
 
 func networkToTerraform(d *schema.ResourceData, n *spec.Network) diag.Diagnostics {
 	d.SetId(*n.Id)
-	d.Set("flow_rules", *n.RulesSource)
-	d.Set("description", *n.Description)
+	if n.RulesSource != nil {
+		d.Set("flow_rules", *n.RulesSource)
+	}
+	// not every backend stores a description; leave it as configured.
+	if n.Description != nil {
+		d.Set("description", *n.Description)
+	}
 	d.Set("name", n.Config.Name)
 	d.Set("creation_time", *n.Config.CreationTime)
-	d.Set("route", mktfRoutes(n.Config.Routes))
-	d.Set("assignment_pool", mktfRanges(n.Config.IpAssignmentPools))
+
+	// routes and pools of subnet blocks are left out, unless they are also
+	// configured on their own.
+	// the data sources have no subnet blocks.
+	subnets, _ := d.Get("subnet").([]interface{})
+	subnetRoutes, subnetPools, err := mkSubnets(subnets)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	configuredRoutes, _ := mkRoutes(d.Get("route"))
+	configuredPools, _ := mkIPRange(d.Get("assignment_pool"))
+
+	d.Set("route", mktfRoutes(withoutSubnetRoutes(n.Config.Routes, subnetRoutes, *configuredRoutes.(*[]spec.Route))))
+	d.Set("assignment_pool", mktfRanges(withoutSubnetPools(n.Config.IpAssignmentPools, subnetPools, *configuredPools.(*[]spec.IPRange))))
 	d.Set("enable_broadcast", ptrBool(n.Config.EnableBroadcast))
 	d.Set("multicast_limit", n.Config.MulticastLimit)
+	d.Set("mtu", n.Config.Mtu)
 	d.Set("private", ptrBool(n.Config.Private))
 	d.Set("assign_ipv4", mktfipv4assign(n.Config.V4AssignMode))
 	d.Set("assign_ipv6", mktfipv6assign(n.Config.V6AssignMode))
-	dns := mktfDNS(n.Config.Dns)
-	logrus.Info("dns:", dns)
-	d.Set("dns", dns)
+	d.Set("dns", mktfDNS(n.Config.Dns))
+	// not every backend has SSO; leave it as configured.
+	if n.Config.SsoConfig != nil {
+		d.Set("sso", mktfSSO(n.Config.SsoConfig))
+	}
+
+	names, err := flowRuleNames(d.Get("flow_rules").(string))
+	if err != nil {
+		logrus.Warnf("Unable to compile the flow rules of ZeroTier Network %s: %v", d.Id(), err)
+	}
+	for key, value := range names {
+		d.Set(key, value)
+	}
+
+	return nil
+}
+
+// ssoModes are the ways Central decides who may join a network with SSO.
+var ssoModes = []string{"default", "email", "group"}
+
+// resourceNetworkCustomizeSSO checks the sso block as a whole, as Central
+// would when it is applied, and refuses it on a local controller.
+func resourceNetworkCustomizeSSO(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("sso") {
+		return nil
+	}
+
+	sso := d.Get("sso").([]interface{})
+	if len(sso) == 0 || sso[0] == nil {
+		return nil
+	}
+
+	config := sso[0].(map[string]interface{})
+	allowed := config["allowed_members"].(*schema.Set).Len()
+
+	switch mode := config["mode"].(string); {
+	case mode == "default" && allowed > 0:
+		return errors.New("sso: allowed_members is only used with mode email or group")
+	case mode != "default" && allowed == 0 && config["enabled"].(bool):
+		return fmt.Errorf("sso: mode %s needs allowed_members, or nobody could join", mode)
+	}
+
+	if !config["enabled"].(bool) {
+		return nil
+	}
+
+	if isLocalBackend(m.(backend)) {
+		return errors.New("sso: SSO is only offered by ZeroTier Central, not a local controller")
+	}
+
+	for _, key := range []string{"issuer", "client_id"} {
+		if config[key].(string) == "" {
+			return fmt.Errorf("sso: %s is required when SSO is enabled", key)
+		}
+	}
+
+	if d.NewValueKnown("private") && !d.Get("private").(bool) {
+		return errors.New("sso: SSO can only be enabled on private networks")
+	}
 
 	return nil
 }
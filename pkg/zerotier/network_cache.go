@@ -0,0 +1,173 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/rules"
+	"golang.org/x/sync/singleflight"
+)
+
+// networkCache keeps what members are planned against: each network and its
+// compiled flow rules, read once however many members use them, and what the
+// plan of each network changes. Terraform plans a network before the members
+// referencing it, so a network's notes are in place by the time its members
+// are planned. Any write to a network drops what was read of it.
+//
+// Like memberCache, it lives as long as a plan or an apply.
+type networkCache struct {
+	backend
+
+	group singleflight.Group
+
+	mutex       sync.Mutex
+	networks    map[string]*spec.Network
+	programs    map[string]*rules.Program
+	generations map[string]uint64
+	changing    map[string]bool
+	// targets holds the planned route targets of networks; nil if they are
+	// unknown until applying.
+	targets map[string][]netip.Prefix
+}
+
+var _ backend = (*networkCache)(nil)
+
+func newNetworkCache(b backend) *networkCache {
+	return &networkCache{
+		backend:     b,
+		networks:    map[string]*spec.Network{},
+		programs:    map[string]*rules.Program{},
+		generations: map[string]uint64{},
+		changing:    map[string]bool{},
+		targets:     map[string][]netip.Prefix{},
+	}
+}
+
+// network reads the network nwid.
+func (c *networkCache) network(ctx context.Context, nwid string) (*spec.Network, error) {
+	c.mutex.Lock()
+	if n, ok := c.networks[nwid]; ok {
+		c.mutex.Unlock()
+		return n, nil
+	}
+	generation := c.generations[nwid]
+	c.mutex.Unlock()
+
+	res, err, _ := c.group.Do(fmt.Sprintf("%s/%d", nwid, generation), func() (interface{}, error) {
+		logrus.Debugf("Reading network %s for its members", nwid)
+
+		n, err := c.backend.GetNetwork(ctx, nwid)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mutex.Lock()
+		if c.generations[nwid] == generation {
+			c.networks[nwid] = n
+		}
+		c.mutex.Unlock()
+
+		return n, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*spec.Network), nil
+}
+
+// flowRules returns the compiled flow rules of the network nwid.
+func (c *networkCache) flowRules(ctx context.Context, nwid string) (*rules.Program, error) {
+	c.mutex.Lock()
+	if p, ok := c.programs[nwid]; ok {
+		c.mutex.Unlock()
+		return p, nil
+	}
+	generation := c.generations[nwid]
+	c.mutex.Unlock()
+
+	p, err := compileNetworkRules(ctx, c, nwid)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	if c.generations[nwid] == generation {
+		c.programs[nwid] = p
+	}
+	c.mutex.Unlock()
+
+	return p, nil
+}
+
+// noteRulesChange records whether the plan changes the flow rules of the
+// network nwid, or can't tell yet.
+func (c *networkCache) noteRulesChange(nwid string, changing bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.changing[nwid] = changing
+}
+
+func (c *networkCache) rulesChanging(nwid string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.changing[nwid]
+}
+
+// noteRouteTargets records the route targets the plan gives the network
+// nwid; nil if they are unknown until applying.
+func (c *networkCache) noteRouteTargets(nwid string, targets []netip.Prefix) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.targets[nwid] = targets
+}
+
+// plannedRouteTargets returns the noted route targets of the network nwid,
+// and whether there are any notes of them.
+func (c *networkCache) plannedRouteTargets(nwid string) ([]netip.Prefix, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	targets, ok := c.targets[nwid]
+	return targets, ok
+}
+
+func (c *networkCache) invalidate(nwid string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.networks, nwid)
+	delete(c.programs, nwid)
+	c.generations[nwid]++
+}
+
+func (c *networkCache) UpdateNetwork(ctx context.Context, networkID string, n *spec.Network) (*spec.Network, error) {
+	defer c.invalidate(networkID)
+	return c.backend.UpdateNetwork(ctx, networkID, n)
+}
+
+func (c *networkCache) UpdateNetworkRules(ctx context.Context, networkID, source string) (string, error) {
+	defer c.invalidate(networkID)
+	return c.backend.UpdateNetworkRules(ctx, networkID, source)
+}
+
+func (c *networkCache) DeleteNetwork(ctx context.Context, networkID string) error {
+	defer c.invalidate(networkID)
+	return c.backend.DeleteNetwork(ctx, networkID)
+}
+
+// underlyingBackend returns the backend under networkCache, if c is one.
+func underlyingBackend(c backend) backend {
+	if cache, ok := c.(*networkCache); ok {
+		return cache.backend
+	}
+
+	return c
+}
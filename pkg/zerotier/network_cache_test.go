@@ -0,0 +1,88 @@
+package zerotier
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
+)
+
+// testNetworkCache returns a network cache in front of a fake with a network
+// using testMemberRules, and the ID of the network.
+func testNetworkCache(t *testing.T) (*fakecentral.Server, *networkCache, string) {
+	srv := fakecentral.New(testAccToken)
+	t.Cleanup(srv.Close)
+
+	c, err := newCentralBackend(srv.URL(), srv.Token, http.DefaultTransport)
+	assert.NoError(t, err)
+
+	n, err := c.NewNetwork(context.Background(), "cache", &spec.Network{})
+	assert.NoError(t, err)
+
+	_, err = c.UpdateNetworkRules(context.Background(), *n.Id, testMemberRules)
+	assert.NoError(t, err)
+
+	return srv, newNetworkCache(c), *n.Id
+}
+
+func TestNetworkCache_FlowRules(t *testing.T) {
+	srv, c, nwid := testNetworkCache(t)
+	srv.SetLatency(20 * time.Millisecond)
+	before := srv.Requests()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			program, err := c.flowRules(context.Background(), nwid)
+			assert.NoError(t, err)
+			assert.Contains(t, program.Tags, "department")
+		}()
+	}
+	wg.Wait()
+
+	reads := srv.Requests() - before
+	assert.NotZero(t, reads)
+
+	_, err := c.flowRules(context.Background(), nwid)
+	assert.NoError(t, err)
+	assert.Equal(t, reads, srv.Requests()-before)
+}
+
+func TestNetworkCache_Invalidation(t *testing.T) {
+	_, c, nwid := testNetworkCache(t)
+
+	_, err := c.flowRules(context.Background(), nwid)
+	assert.NoError(t, err)
+
+	_, err = c.UpdateNetworkRules(context.Background(), nwid, "tag location id 1002;\naccept;\n")
+	assert.NoError(t, err)
+
+	program, err := c.flowRules(context.Background(), nwid)
+	assert.NoError(t, err)
+	assert.Contains(t, program.Tags, "location")
+	assert.NotContains(t, program.Tags, "department")
+
+	assert.NoError(t, c.DeleteNetwork(context.Background(), nwid))
+
+	_, err = c.flowRules(context.Background(), nwid)
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestNetworkCache_RulesChanging(t *testing.T) {
+	_, c, nwid := testNetworkCache(t)
+
+	assert.False(t, networkRulesChanging(c, nwid))
+
+	c.noteRulesChange(nwid, true)
+	assert.True(t, networkRulesChanging(c, nwid))
+
+	// a provider without the cache can't tell.
+	assert.False(t, networkRulesChanging(c.backend, nwid))
+}
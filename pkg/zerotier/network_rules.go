@@ -0,0 +1,518 @@
+package zerotier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/rules"
+)
+
+// defaultFlowRules are the rules of a network that does not configure any.
+const defaultFlowRules = "accept;"
+
+var (
+	flowRuleActions = []string{"accept", "drop", "break", "tee", "watch", "redirect", "priority"}
+	flowRuleMatches = []string{
+		"ztsrc", "ztdest", "vlan", "vlanpcp", "vlandei", "ethertype", "macsrc",
+		"macdest", "ipsrc", "ipdest", "iptos", "ipprotocol", "icmp", "sport",
+		"dport", "chr", "framesize", "random", "tdiff", "tand", "tor", "txor",
+		"teq", "tseq", "treq",
+	}
+	flowRuleTagMatches = []string{"tdiff", "tand", "tor", "txor", "teq", "tseq", "treq"}
+
+	// words of the rules language can't contain these.
+	validFlowRuleWord = validation.StringMatch(regexp.MustCompile(`^[^\s;#()]+$`), "must be a single word without ;, #, ( or )")
+)
+
+// structuredFlowRules are the attributes that render to flow_rules.
+var structuredFlowRules = []string{"rule", "tag", "capability"}
+
+// flowRuleResource is a rule block, used in both the network and its
+// capabilities.
+func flowRuleResource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"action": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(flowRuleActions, false)),
+				Description:      "What to do with packets matching this rule: one of " + strings.Join(flowRuleActions, ", ") + ".",
+			},
+			"address": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validFlowRuleWord),
+				Description:      "The ZeroTier address to send packets to, for tee, watch and redirect.",
+			},
+			"length": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     -1,
+				Description: "How many bytes of each packet to send for tee and watch; -1 sends all of it.",
+			},
+			"priority": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "The QoS bucket, 0 to 7, for priority.",
+			},
+			"match": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"type": {
+							Type:             schema.TypeString,
+							Required:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(flowRuleMatches, false)),
+							Description:      "What to match, such as ethertype, ipprotocol or dport. See the rules language documentation for the full list.",
+						},
+						"value": {
+							Type:             schema.TypeString,
+							Required:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validFlowRuleWord),
+							Description:      "The value to match, as it would be written in the rules language: a number, a name such as `ipv4` or `tcp`, a range such as `80-443`, an address or a network.",
+						},
+						"tag": {
+							Type:             schema.TypeString,
+							Optional:         true,
+							ValidateDiagFunc: validation.ToDiagFunc(validFlowRuleWord),
+							Description:      "The tag name or ID to compare, for " + strings.Join(flowRuleTagMatches, ", ") + ".",
+						},
+						"mask": {
+							Type:        schema.TypeInt,
+							Optional:    true,
+							Description: "The mask applied to the type of service, for iptos.",
+						},
+						"code": {
+							Type:        schema.TypeInt,
+							Optional:    true,
+							Default:     -1,
+							Description: "The ICMP code, for icmp; -1 matches any code.",
+						},
+						"not": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Invert the match.",
+						},
+						"or": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Combine the match with the one before it with OR instead of AND.",
+						},
+					},
+				},
+				Description: "The conditions of the rule, in order. A rule without matches applies to every packet.",
+			},
+		},
+	}
+}
+
+func flowRuleTagResource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validFlowRuleWord),
+				Description:      "The name of the tag.",
+			},
+			"id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "The ID of the tag, unique in the network.",
+			},
+			"default": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validFlowRuleWord),
+				Description:      "The value of the tag for members that do not set it: a number, or one of its enums or flags.",
+			},
+			"enums": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "Names for values of the tag.",
+			},
+			"flags": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "Names for bits of the tag, 0 to 31.",
+			},
+		},
+	}
+}
+
+func flowRuleCapabilityResource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validation.ToDiagFunc(validFlowRuleWord),
+				Description:      "The name of the capability.",
+			},
+			"id": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "The ID of the capability, unique in the network.",
+			},
+			"rule": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        flowRuleResource(),
+				Description: "The rules granted by the capability, applied in order.",
+			},
+		},
+	}
+}
+
+// flowRulesConfigured reports whether any of the structured rule blocks are
+// set.
+func flowRulesConfigured(get func(string) interface{}) bool {
+	for _, key := range structuredFlowRules {
+		if len(get(key).([]interface{})) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// networkRulesSource is the rules source to send for the network: the
+// rendered blocks if there are any, or else flow_rules.
+func networkRulesSource(d *schema.ResourceData) (string, error) {
+	if flowRulesConfigured(d.Get) {
+		return renderFlowRules(d.Get)
+	}
+
+	return d.Get("flow_rules").(string), nil
+}
+
+// resourceNetworkCustomizeFlowRules plans flow_rules: the rendered blocks
+// when they are used, or the default rules when neither they nor flow_rules
+// are set.
+func resourceNetworkCustomizeFlowRules(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	var source string
+
+	switch {
+	case flowRulesConfigured(d.Get):
+		for _, key := range structuredFlowRules {
+			if !d.NewValueKnown(key) {
+				return d.SetNewComputed("flow_rules")
+			}
+		}
+
+		var err error
+		if source, err = renderFlowRules(d.Get); err != nil {
+			return err
+		}
+	case d.GetRawConfig().GetAttr("flow_rules").IsNull():
+		source = defaultFlowRules
+	default:
+		return nil
+	}
+
+	if old, _ := d.GetChange("flow_rules"); equivalentFlowRules(old.(string), source) {
+		return nil
+	}
+
+	return d.SetNew("flow_rules", source)
+}
+
+// flowRuleNameAttributes are the computed attributes holding the names
+// defined by flow_rules.
+var flowRuleNameAttributes = []string{"tags_by_name", "capabilities_by_name", "tag_enums", "tag_flags"}
+
+// flowRuleNames compiles source and returns the values of
+// flowRuleNameAttributes. They are empty if it doesn't compile.
+func flowRuleNames(source string) (map[string]map[string]interface{}, error) {
+	names := map[string]map[string]interface{}{}
+	for _, key := range flowRuleNameAttributes {
+		names[key] = map[string]interface{}{}
+	}
+
+	p, err := rules.Compile(source)
+	if err != nil {
+		return names, err
+	}
+
+	for name, tag := range p.Tags {
+		names["tags_by_name"][name] = int(tag.ID)
+
+		for enum, value := range tag.Enums {
+			names["tag_enums"][name+"."+enum] = int(value)
+		}
+		for flag, bit := range tag.Flags {
+			names["tag_flags"][name+"."+flag] = int(bit)
+		}
+	}
+
+	for name, capability := range p.Capabilities {
+		names["capabilities_by_name"][name] = int(capability.ID)
+	}
+
+	return names, nil
+}
+
+// resourceNetworkCustomizeFlowRuleNames plans the names defined by
+// flow_rules, so that members can use them in the same plan. Members of a
+// network whose rules change resolve their names when applying.
+func resourceNetworkCustomizeFlowRuleNames(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if cache, ok := m.(*networkCache); ok && d.Id() != "" {
+		cache.noteRulesChange(d.Id(), !d.NewValueKnown("flow_rules") || d.HasChange("flow_rules"))
+	}
+
+	if !d.NewValueKnown("flow_rules") {
+		for _, key := range flowRuleNameAttributes {
+			if err := d.SetNewComputed(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if !d.HasChange("flow_rules") {
+		return nil
+	}
+
+	// invalid rules are reported by flow_rules' own validation.
+	names, err := flowRuleNames(d.Get("flow_rules").(string))
+	if err != nil {
+		return nil
+	}
+
+	for key, value := range names {
+		if err := d.SetNew(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// equivalentFlowRules reports whether two rules sources differ only in
+// layout and comments. Sources that can't be formatted are only equivalent
+// if they are identical.
+func equivalentFlowRules(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	fa, err := rules.Format(a)
+	if err != nil {
+		return false
+	}
+
+	fb, err := rules.Format(b)
+	if err != nil {
+		return false
+	}
+
+	return fa == fb
+}
+
+// suppressEquivalentFlowRules hides changes to flow_rules that don't change
+// the rules, such as Central normalizing whitespace or line endings.
+func suppressEquivalentFlowRules(k, old, new string, d *schema.ResourceData) bool {
+	return equivalentFlowRules(old, new)
+}
+
+// renderFlowRules renders the rule, tag and capability blocks to rules
+// source, one statement to a line: tags, then capabilities, then rules. The
+// result is compiled so that mistakes are reported against the block that
+// caused them.
+func renderFlowRules(get func(string) interface{}) (string, error) {
+	var (
+		lines []string
+		// origins holds the block each line came from.
+		origins []string
+	)
+
+	add := func(origin, line string) {
+		lines = append(lines, line)
+		origins = append(origins, origin)
+	}
+
+	for i, t := range get("tag").([]interface{}) {
+		add(fmt.Sprintf("tag.%d", i), renderFlowRuleTag(t.(map[string]interface{})))
+	}
+
+	for i, c := range get("capability").([]interface{}) {
+		capability := c.(map[string]interface{})
+		origin := fmt.Sprintf("capability.%d", i)
+
+		header := fmt.Sprintf("cap %s id %d", capability["name"], capability["id"])
+		capRules := capability["rule"].([]interface{})
+
+		// without rules, the header has to end before the block does.
+		if len(capRules) == 0 {
+			header += ";"
+		}
+
+		add(origin, header)
+
+		for j, r := range capRules {
+			line, err := renderFlowRule(r)
+			if err != nil {
+				return "", fmt.Errorf("%s.rule.%d: %w", origin, j, err)
+			}
+
+			add(fmt.Sprintf("%s.rule.%d", origin, j), "  "+line)
+		}
+
+		add(origin, ";")
+	}
+
+	for i, r := range get("rule").([]interface{}) {
+		line, err := renderFlowRule(r)
+		if err != nil {
+			return "", fmt.Errorf("rule.%d: %w", i, err)
+		}
+
+		add(fmt.Sprintf("rule.%d", i), line)
+	}
+
+	source := strings.Join(lines, "\n") + "\n"
+
+	if _, err := rules.Compile(source); err != nil {
+		var cerr *rules.Error
+		if errors.As(err, &cerr) && cerr.Line <= len(origins) {
+			return "", fmt.Errorf("%s: %s", origins[cerr.Line-1], cerr.Message)
+		}
+
+		return "", err
+	}
+
+	return source, nil
+}
+
+func renderFlowRuleTag(tag map[string]interface{}) string {
+	words := []string{"tag", tag["name"].(string), "id", fmt.Sprint(tag["id"])}
+
+	if def := tag["default"].(string); def != "" {
+		words = append(words, "default", def)
+	}
+
+	for _, kind := range []string{"enum", "flag"} {
+		values := tag[kind+"s"].(map[string]interface{})
+
+		names := []string{}
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if values[names[i]].(int) != values[names[j]].(int) {
+				return values[names[i]].(int) < values[names[j]].(int)
+			}
+			return names[i] < names[j]
+		})
+
+		for _, name := range names {
+			words = append(words, kind, fmt.Sprint(values[name]), name)
+		}
+	}
+
+	return strings.Join(words, " ") + ";"
+}
+
+// renderFlowRule renders a rule block, checking each of its arguments is
+// given where it is needed and only there.
+func renderFlowRule(i interface{}) (string, error) {
+	rule := i.(map[string]interface{})
+	action := rule["action"].(string)
+	address := rule["address"].(string)
+
+	words := []string{action}
+
+	switch action {
+	case "tee", "watch", "redirect":
+		if address == "" {
+			return "", fmt.Errorf("%s needs an address", action)
+		}
+
+		if action != "redirect" {
+			words = append(words, fmt.Sprint(rule["length"]))
+		} else if rule["length"].(int) != -1 {
+			return "", errors.New("length is only used by tee and watch")
+		}
+
+		words = append(words, address)
+	case "priority":
+		words = append(words, fmt.Sprint(rule["priority"]))
+	default:
+		if address != "" {
+			return "", errors.New("address is only used by tee, watch and redirect")
+		}
+		if rule["length"].(int) != -1 {
+			return "", errors.New("length is only used by tee and watch")
+		}
+	}
+
+	if action != "priority" && rule["priority"].(int) != 0 {
+		return "", errors.New("priority is only used by the priority action")
+	}
+
+	for j, m := range rule["match"].([]interface{}) {
+		match := m.(map[string]interface{})
+		matchType := match["type"].(string)
+
+		if match["or"].(bool) {
+			if j == 0 {
+				return "", fmt.Errorf("match.%d: or needs a match before it", j)
+			}
+
+			words = append(words, "or")
+		} else if j > 0 {
+			words = append(words, "and")
+		}
+
+		if match["not"].(bool) {
+			words = append(words, "not")
+		}
+
+		words = append(words, matchType)
+
+		tag := match["tag"].(string)
+		isTagMatch := false
+		for _, t := range flowRuleTagMatches {
+			isTagMatch = isTagMatch || t == matchType
+		}
+
+		switch {
+		case isTagMatch && tag == "":
+			return "", fmt.Errorf("match.%d: %s needs a tag", j, matchType)
+		case !isTagMatch && tag != "":
+			return "", fmt.Errorf("match.%d: tag is only used by %s", j, strings.Join(flowRuleTagMatches, ", "))
+		case isTagMatch:
+			words = append(words, tag)
+		}
+
+		switch {
+		case matchType == "iptos":
+			words = append(words, fmt.Sprint(match["mask"]))
+		case match["mask"].(int) != 0:
+			return "", fmt.Errorf("match.%d: mask is only used by iptos", j)
+		}
+
+		words = append(words, match["value"].(string))
+
+		switch {
+		case matchType == "icmp":
+			words = append(words, fmt.Sprint(match["code"]))
+		case match["code"].(int) != -1:
+			return "", fmt.Errorf("match.%d: code is only used by icmp", j)
+		}
+	}
+
+	return strings.Join(words, " ") + ";", nil
+}
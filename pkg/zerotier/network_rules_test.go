@@ -0,0 +1,644 @@
+package zerotier
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/rules"
+)
+
+func testFlowRuleBlocks(t *testing.T, raw map[string]interface{}) *schema.ResourceData {
+	t.Helper()
+
+	return schema.TestResourceDataRaw(t, resourceNetwork().Schema, raw)
+}
+
+func TestRenderFlowRules_RoundTrip(t *testing.T) {
+	d := testFlowRuleBlocks(t, map[string]interface{}{
+		"tag": []interface{}{
+			map[string]interface{}{
+				"name":    "department",
+				"id":      1000,
+				"default": "sales",
+				"enums":   map[string]interface{}{"sales": 100, "engineering": 200},
+			},
+			map[string]interface{}{
+				"name":  "roles",
+				"id":    1001,
+				"flags": map[string]interface{}{"admin": 0, "ops": 1},
+			},
+		},
+		"capability": []interface{}{
+			map[string]interface{}{
+				"name": "superuser",
+				"id":   1,
+				"rule": []interface{}{
+					map[string]interface{}{"action": "accept"},
+				},
+			},
+			map[string]interface{}{
+				"name": "nothing",
+				"id":   2,
+			},
+		},
+		"rule": []interface{}{
+			map[string]interface{}{
+				"action": "drop",
+				"match": []interface{}{
+					map[string]interface{}{"type": "ethertype", "value": "ipv4", "not": true},
+					map[string]interface{}{"type": "ethertype", "value": "arp", "not": true},
+					map[string]interface{}{"type": "ethertype", "value": "ipv6", "not": true},
+				},
+			},
+			map[string]interface{}{
+				"action":  "tee",
+				"length":  128,
+				"address": "abcdef0123",
+				"match": []interface{}{
+					map[string]interface{}{"type": "ipprotocol", "value": "tcp"},
+					map[string]interface{}{"type": "dport", "value": "22"},
+					map[string]interface{}{"type": "dport", "value": "80-443", "or": true},
+				},
+			},
+			map[string]interface{}{
+				"action": "accept",
+				"match": []interface{}{
+					map[string]interface{}{"type": "icmp", "value": "8", "code": 0},
+					map[string]interface{}{"type": "iptos", "value": "16-32", "mask": 252, "or": true},
+					map[string]interface{}{"type": "teq", "tag": "department", "value": "engineering"},
+				},
+			},
+			map[string]interface{}{"action": "priority", "priority": 3},
+			map[string]interface{}{"action": "accept"},
+		},
+	})
+
+	source, err := renderFlowRules(d.Get)
+	assert.NoError(t, err)
+	assert.Equal(t, `tag department id 1000 default sales enum 100 sales enum 200 engineering;
+tag roles id 1001 flag 0 admin flag 1 ops;
+cap superuser id 1
+  accept;
+;
+cap nothing id 2;
+;
+drop not ethertype ipv4 and not ethertype arp and not ethertype ipv6;
+tee 128 abcdef0123 ipprotocol tcp and dport 22 or dport 80-443;
+accept icmp 8 0 or iptos 252 16-32 and teq department engineering;
+priority 3;
+accept;
+`, source)
+
+	rendered, err := rules.Compile(source)
+	assert.NoError(t, err)
+
+	handwritten, err := rules.Compile(`
+tag department
+  id 1000
+  enum 100 sales
+  enum 200 engineering
+  default sales
+;
+tag roles id 1001 flag 0 admin flag 1 ops;
+
+cap superuser
+  id 1
+  accept;
+;
+
+cap nothing id 2;;
+
+drop
+  not ethertype ipv4
+  and not ethertype arp
+  and not ethertype ipv6
+;
+tee 128 abcdef0123 ipprotocol tcp and dport 22 or dport 80-443;
+accept icmp 8 0 or iptos 252 16-32 and teq department engineering;
+priority 3;
+accept;
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, handwritten, rendered)
+
+	// the rendered source is what gets planned, so rendering again has to
+	// give the same text.
+	again, err := renderFlowRules(d.Get)
+	assert.NoError(t, err)
+	assert.Equal(t, source, again)
+}
+
+func TestRenderFlowRules_Errors(t *testing.T) {
+	tests := map[string]struct {
+		raw      map[string]interface{}
+		expected string
+	}{
+		"tee without address": {
+			raw: map[string]interface{}{
+				"rule": []interface{}{
+					map[string]interface{}{"action": "tee"},
+				},
+			},
+			expected: "rule.0: tee needs an address",
+		},
+		"address on accept": {
+			raw: map[string]interface{}{
+				"rule": []interface{}{
+					map[string]interface{}{"action": "accept"},
+					map[string]interface{}{"action": "accept", "address": "abcdef0123"},
+				},
+			},
+			expected: "rule.1: address is only used by tee, watch and redirect",
+		},
+		"length on redirect": {
+			raw: map[string]interface{}{
+				"rule": []interface{}{
+					map[string]interface{}{"action": "redirect", "address": "abcdef0123", "length": 64},
+				},
+			},
+			expected: "rule.0: length is only used by tee and watch",
+		},
+		"priority on drop": {
+			raw: map[string]interface{}{
+				"rule": []interface{}{
+					map[string]interface{}{"action": "drop", "priority": 2},
+				},
+			},
+			expected: "rule.0: priority is only used by the priority action",
+		},
+		"first match with or": {
+			raw: map[string]interface{}{
+				"rule": []interface{}{
+					map[string]interface{}{
+						"action": "drop",
+						"match": []interface{}{
+							map[string]interface{}{"type": "vlan", "value": "1", "or": true},
+						},
+					},
+				},
+			},
+			expected: "rule.0: match.0: or needs a match before it",
+		},
+		"tag match without tag": {
+			raw: map[string]interface{}{
+				"rule": []interface{}{
+					map[string]interface{}{
+						"action": "drop",
+						"match": []interface{}{
+							map[string]interface{}{"type": "teq", "value": "1"},
+						},
+					},
+				},
+			},
+			expected: "rule.0: match.0: teq needs a tag",
+		},
+		"code on dport": {
+			raw: map[string]interface{}{
+				"rule": []interface{}{
+					map[string]interface{}{
+						"action": "drop",
+						"match": []interface{}{
+							map[string]interface{}{"type": "dport", "value": "22", "code": 3},
+						},
+					},
+				},
+			},
+			expected: "rule.0: match.0: code is only used by icmp",
+		},
+		"bad rule in capability": {
+			raw: map[string]interface{}{
+				"capability": []interface{}{
+					map[string]interface{}{
+						"name": "ops",
+						"id":   1,
+						"rule": []interface{}{
+							map[string]interface{}{"action": "accept"},
+							map[string]interface{}{"action": "watch"},
+						},
+					},
+				},
+			},
+			expected: "capability.0.rule.1: watch needs an address",
+		},
+		"compile error in rule": {
+			raw: map[string]interface{}{
+				"rule": []interface{}{
+					map[string]interface{}{"action": "accept"},
+					map[string]interface{}{
+						"action": "drop",
+						"match": []interface{}{
+							map[string]interface{}{"type": "ethertype", "value": "ipv5"},
+						},
+					},
+				},
+			},
+			expected: `rule.1: "ipv5" is not a number or one of aarp, arp, atalk, ipv4, ipv6, ipx_a, ipx_b, rarp, wol`,
+		},
+		"unknown tag": {
+			raw: map[string]interface{}{
+				"rule": []interface{}{
+					map[string]interface{}{
+						"action": "drop",
+						"match": []interface{}{
+							map[string]interface{}{"type": "teq", "tag": "department", "value": "1"},
+						},
+					},
+				},
+			},
+			expected: `rule.0: undefined tag "department"`,
+		},
+		"duplicate tag": {
+			raw: map[string]interface{}{
+				"tag": []interface{}{
+					map[string]interface{}{"name": "department", "id": 1},
+					map[string]interface{}{"name": "department", "id": 2},
+				},
+			},
+			expected: `tag.1: tag "department" is already defined`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := renderFlowRules(testFlowRuleBlocks(t, test.raw).Get)
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func TestAccNetwork_ruleBlocks(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckNetworkDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-rule-blocks"
+
+  tag {
+    name  = "department"
+    id    = 1000
+    enums = {
+      sales       = 100
+      engineering = 200
+    }
+  }
+
+  capability {
+    name = "superuser"
+    id   = 1
+
+    rule {
+      action = "accept"
+    }
+  }
+
+  rule {
+    action = "drop"
+
+    match {
+      type  = "ethertype"
+      value = "ipv4"
+      not   = true
+    }
+
+    match {
+      type  = "ethertype"
+      value = "arp"
+      not   = true
+    }
+  }
+
+  rule {
+    action = "accept"
+
+    match {
+      type  = "teq"
+      tag   = "department"
+      value = "engineering"
+    }
+  }
+}
+`),
+				Check: resource.TestCheckResourceAttr("zerotier_network.test", "flow_rules", `tag department id 1000 enum 100 sales enum 200 engineering;
+cap superuser id 1
+  accept;
+;
+drop not ethertype ipv4 and not ethertype arp;
+accept teq department engineering;
+`),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-rule-blocks"
+
+  rule {
+    action = "drop"
+  }
+}
+`),
+				Check: resource.TestCheckResourceAttr("zerotier_network.test", "flow_rules", "drop;\n"),
+			},
+			{
+				// dropping the blocks goes back to the default rules.
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-rule-blocks"
+}
+`),
+				Check: resource.TestCheckResourceAttr("zerotier_network.test", "flow_rules", defaultFlowRules),
+			},
+		},
+	})
+}
+
+func TestAccNetwork_ruleBlocksConflict(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name       = "acc-network-rule-blocks"
+  flow_rules = "accept;"
+
+  rule {
+    action = "drop"
+  }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`"rule": conflicts with flow_rules`),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-rule-blocks"
+
+  rule {
+    action = "drop"
+
+    match {
+      type  = "ethertype"
+      value = "ipv5"
+    }
+  }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`rule.0: "ipv5" is not a number`),
+			},
+		},
+	})
+
+	assert.Empty(t, srv.NetworkIDs())
+}
+
+func TestSuppressEquivalentFlowRules(t *testing.T) {
+	tests := map[string]struct {
+		old      string
+		new      string
+		suppress bool
+	}{
+		"identical": {
+			old:      "accept;",
+			new:      "accept;",
+			suppress: true,
+		},
+		"trailing newline": {
+			old:      "accept;\n",
+			new:      "accept;",
+			suppress: true,
+		},
+		"line endings": {
+			old:      "drop\r\n  not ethertype ipv4\r\n;\r\naccept;\r\n",
+			new:      "drop\n  not ethertype ipv4\n;\naccept;\n",
+			suppress: true,
+		},
+		"comments removed": {
+			old:      "drop not ethertype ipv4;\naccept;\n",
+			new:      "# only ipv4\ndrop not ethertype ipv4; # for now\naccept;\n",
+			suppress: true,
+		},
+		"reindented": {
+			old:      "cap superuser id 1 accept;\n;\n",
+			new:      "cap superuser\n\tid 1\n\taccept;\n;",
+			suppress: true,
+		},
+		"missing final semicolon": {
+			old:      "accept;",
+			new:      "accept",
+			suppress: true,
+		},
+		"stray semicolons": {
+			old:      "accept;",
+			new:      ";accept;;",
+			suppress: true,
+		},
+		"different action": {
+			old:      "accept;",
+			new:      "drop;",
+			suppress: false,
+		},
+		"reordered": {
+			old:      "drop chr inbound;\naccept;",
+			new:      "accept;\ndrop chr inbound;",
+			suppress: false,
+		},
+		"commented out": {
+			old:      "drop chr inbound;\naccept;",
+			new:      "# drop chr inbound;\naccept;",
+			suppress: false,
+		},
+		"split statement": {
+			old:      "drop chr inbound;",
+			new:      "drop; chr inbound;",
+			suppress: false,
+		},
+		"closing a cap": {
+			old:      "cap superuser id 1 accept;\naccept;",
+			new:      "cap superuser id 1 accept;\n;\naccept;",
+			suppress: false,
+		},
+		"case": {
+			old:      "tag dept id 1 enum 1 sales;",
+			new:      "tag dept id 1 enum 1 Sales;",
+			suppress: false,
+		},
+		"unbalanced": {
+			old:      "include allow(22;",
+			new:      "include allow(22 ;",
+			suppress: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.suppress, suppressEquivalentFlowRules("flow_rules", test.old, test.new, nil))
+			assert.Equal(t, test.suppress, suppressEquivalentFlowRules("flow_rules", test.new, test.old, nil))
+		})
+	}
+}
+
+func TestAccNetwork_flowRulesNormalized(t *testing.T) {
+	srv := testAccCentral(t)
+	config := testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name       = "acc-network-normalized"
+  flow_rules = <<-EOT
+    # only ipv4 and arp
+    drop
+      not ethertype ipv4
+      and not ethertype arp
+    ;
+    accept;
+  EOT
+}
+`)
+
+	var id string
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckNetworkDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: func(s *terraform.State) error {
+					id = s.RootModule().Resources["zerotier_network.test"].Primary.ID
+					return nil
+				},
+			},
+			{
+				// Central rewriting the rules without changing them.
+				PreConfig: func() {
+					srv.SetNetwork(id, &spec.Network{
+						RulesSource: stringPtr("drop not ethertype ipv4 and not ethertype arp;\r\naccept;\r\n"),
+					})
+				},
+				Config:   config,
+				PlanOnly: true,
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name       = "acc-network-normalized"
+  flow_rules = <<-EOT
+    drop
+      not ethertype ipv4
+    ;
+    accept;
+  EOT
+}
+`),
+				Check: resource.TestCheckResourceAttr("zerotier_network.test", "flow_rules", "drop\n  not ethertype ipv4\n;\naccept;\n"),
+			},
+		},
+	})
+}
+
+func TestFlowRuleNames(t *testing.T) {
+	names, err := flowRuleNames(`
+tag department id 1000 enum 100 sales enum 200 engineering;
+tag roles id 1001 flag 0 admin flag 1 ops;
+cap superuser id 1 accept;;
+cap nothing id 2;;
+accept;
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"tags_by_name":         {"department": 1000, "roles": 1001},
+		"capabilities_by_name": {"superuser": 1, "nothing": 2},
+		"tag_enums":            {"department.sales": 100, "department.engineering": 200},
+		"tag_flags":            {"roles.admin": 0, "roles.ops": 1},
+	}, names)
+
+	names, err = flowRuleNames("accept;")
+	assert.NoError(t, err)
+	for _, key := range flowRuleNameAttributes {
+		assert.Empty(t, names[key], key)
+	}
+
+	names, err = flowRuleNames("tag department;")
+	assert.Error(t, err)
+	for _, key := range flowRuleNameAttributes {
+		assert.Empty(t, names[key], key)
+	}
+}
+
+func TestAccNetwork_flowRuleNames(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckNetworkDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name       = "acc-network-names"
+  flow_rules = <<-EOT
+    tag department id 1000 enum 100 sales enum 200 engineering;
+    tag roles id 1001 flag 0 admin flag 1 ops;
+    cap superuser id 1
+      accept;
+    ;
+    accept;
+  EOT
+}
+
+data "zerotier_network" "test" {
+  id = zerotier_network.test.id
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_network.test", "tags_by_name.%", "2"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "tags_by_name.department", "1000"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "tags_by_name.roles", "1001"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "capabilities_by_name.%", "1"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "capabilities_by_name.superuser", "1"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "tag_enums.%", "2"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "tag_enums.department.sales", "100"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "tag_flags.roles.ops", "1"),
+					resource.TestCheckResourceAttr("data.zerotier_network.test", "tags_by_name.department", "1000"),
+					resource.TestCheckResourceAttr("data.zerotier_network.test", "capabilities_by_name.superuser", "1"),
+					resource.TestCheckResourceAttr("data.zerotier_network.test", "tag_enums.department.engineering", "200"),
+				),
+			},
+			{
+				// the names are planned with the rules, so they can be used
+				// by other resources in the same apply.
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name       = "acc-network-names"
+  flow_rules = <<-EOT
+    tag department id 1000 enum 100 sales enum 300 engineering;
+    accept;
+  EOT
+}
+
+resource "terraform_data" "engineering" {
+  input = zerotier_network.test.tag_enums["department.engineering"]
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_network.test", "tags_by_name.%", "1"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "capabilities_by_name.%", "0"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "tag_flags.%", "0"),
+					resource.TestCheckResourceAttr("terraform_data.engineering", "output", "300"),
+				),
+			},
+		},
+	})
+}
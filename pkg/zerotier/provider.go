@@ -2,11 +2,13 @@ package zerotier
 
 import (
 	"context"
-	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/sirupsen/logrus"
 	"github.com/zerotier/go-ztcentral"
 )
@@ -32,20 +34,73 @@ func Provider() *schema.Provider {
 			},
 			"zerotier_central_token": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("ZEROTIER_CENTRAL_TOKEN", nil),
-				Description: "ZeroTier Central API Token; you can generate a new one at https://my.zerotier.com/account.",
+				Description: "ZeroTier Central API Token; you can generate a new one at https://my.zerotier.com/account. Required unless `local_controller` is configured.",
+			},
+			"local_controller": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"url": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							DefaultFunc: schema.EnvDefaultFunc("ZEROTIER_CONTROLLER_URL", defaultLocalControllerURL),
+							Description: "Service API endpoint of the zerotier-one node running the controller.",
+						},
+						"token": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							DefaultFunc: schema.EnvDefaultFunc("ZEROTIER_CONTROLLER_TOKEN", nil),
+							Description: "Service API token of the zerotier-one node, the contents of its authtoken.secret. If not set, it is read from `token_file`.",
+						},
+						"token_file": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     defaultLocalControllerTokenFile,
+							Description: "File to read the service API token from when `token` is not set.",
+						},
+					},
+				},
+				Description: "Manage a self-hosted zerotier-one controller through its local JSON API instead of ZeroTier Central. The local controller has no descriptions, hidden members or API tokens.",
+			},
+			"max_retries": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("ZEROTIER_MAX_RETRIES", defaultMaxRetries),
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+				Description:      "How many times to retry an API request that failed with a transient error, such as being rate limited. Set to 0 to disable retries.",
+			},
+			"max_retry_wait": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("ZEROTIER_MAX_RETRY_WAIT", defaultMaxRetryWait),
+				ValidateDiagFunc: validDuration,
+				Description:      "The most time to spend waiting between retries of a single API request, as a duration such as `90s` or `5m`. Delays requested by the API with Retry-After count towards this.",
+			},
+			"max_concurrent_requests": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				DefaultFunc:      schema.EnvDefaultFunc("ZEROTIER_MAX_CONCURRENT_REQUESTS", 0),
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+				Description:      "The most API requests to have in flight at once, across all resources. Lower this if a high `-parallelism` gets you rate limited. 0 means no limit.",
 			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"zerotier_identity": resourceIdentity(),
-			"zerotier_network":  resourceNetwork(),
-			"zerotier_member":   resourceMember(),
-			"zerotier_token":    resourceToken(),
+			"zerotier_identity":      resourceIdentity(),
+			"zerotier_network":       resourceNetwork(),
+			"zerotier_member":        resourceMember(),
+			"zerotier_token":         resourceToken(),
+			"zerotier_ip_allocation": resourceIPAllocation(),
 		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"zerotier_network": dataSourceNetwork(),
-			"zerotier_members": dataSourceMembers(),
+			"zerotier_network":  dataSourceNetwork(),
+			"zerotier_networks": dataSourceNetworks(),
+			"zerotier_member":   dataSourceMember(),
+			"zerotier_members":  dataSourceMembers(),
 		},
 		ConfigureContextFunc: providerConfigure,
 	}
@@ -54,27 +109,57 @@ func Provider() *schema.Provider {
 func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
 	defer logrus.Debug("ZeroTier provider configured")
 
+	maxRetryWait, err := time.ParseDuration(d.Get("max_retry_wait").(string))
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	// retries wait outside the limit, so a throttled request does not hold up
+	// the others.
+	transport := newRetryTransport(
+		newLimitTransport(http.DefaultTransport, d.Get("max_concurrent_requests").(int)),
+		d.Get("max_retries").(int),
+		maxRetryWait,
+	)
+
+	if local := d.Get("local_controller").([]interface{}); len(local) > 0 && local[0] != nil {
+		return configureLocalController(local[0].(map[string]interface{}), transport)
+	}
+
 	ztControllerToken := d.Get("zerotier_central_token").(string)
 	ztControllerURL := d.Get("zerotier_central_url").(string)
 
 	if ztControllerToken != "" {
-		// NOTE this whole block is extremely order-dependent
-		//      deal with it
-		if ztControllerURL != "" {
-			ztcentral.BaseURLV1 = ztControllerURL
+		if ztControllerURL == "" {
+			ztControllerURL = ztcentral.BaseURLV1
 		}
 
-		c, err := ztcentral.NewClient(ztControllerToken)
+		c, err := newCentralBackend(ztControllerURL, ztControllerToken, transport)
 		if err != nil {
 			return nil, diag.FromErr(err)
 		}
 
 		logrus.Debug("Token configured successfully")
 
-		c.SetUserAgent(fmt.Sprintf("terraform-provider-zerotier/%s", Version))
-
-		return c, nil
+		return newNetworkCache(newMemberCache(c)), nil
 	}
 
 	return nil, diag.Errorf("zerotier_central_token must be specified, or ZEROTIER_CENTRAL_TOKEN must be specified in environment")
 }
+
+func configureLocalController(settings map[string]interface{}, transport http.RoundTripper) (interface{}, diag.Diagnostics) {
+	url := settings["url"].(string)
+	token := settings["token"].(string)
+
+	if token == "" {
+		var err error
+		token, err = readLocalToken(settings["token_file"].(string))
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+	}
+
+	logrus.Debugf("Using local controller at %s", url)
+
+	return newNetworkCache(newLocalBackend(url, token, transport)), nil
+}
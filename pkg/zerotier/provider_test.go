@@ -0,0 +1,41 @@
+package zerotier
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
+)
+
+// testAccToken is the token the fake central accepts in acceptance tests.
+const testAccToken = "this-is-not-a-real-central-token"
+
+var testAccProviderFactories = map[string]func() (*schema.Provider, error){
+	"zerotier": func() (*schema.Provider, error) {
+		return Provider(), nil
+	},
+}
+
+func TestProvider(t *testing.T) {
+	if err := Provider().InternalValidate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testAccCentral starts a fake central for the lifetime of the test.
+func testAccCentral(t *testing.T) *fakecentral.Server {
+	srv := fakecentral.New(testAccToken)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testAccConfig prefixes config with a provider block pointing at srv.
+func testAccConfig(srv *fakecentral.Server, config string) string {
+	return fmt.Sprintf(`
+provider "zerotier" {
+  zerotier_central_url   = %q
+  zerotier_central_token = %q
+}
+`, srv.URL(), srv.Token) + config
+}
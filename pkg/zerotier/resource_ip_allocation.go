@@ -0,0 +1,322 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/sirupsen/logrus"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+// allocationLocks serializes allocations on the same network, so that
+// concurrent allocations in one apply never pick the same address.
+var allocationLocks = &networkLocks{locks: map[string]*sync.Mutex{}}
+
+type networkLocks struct {
+	mutex sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks networkID and returns the function that unlocks it.
+func (l *networkLocks) lock(networkID string) func() {
+	l.mutex.Lock()
+	lock, ok := l.locks[networkID]
+	if !ok {
+		lock = &sync.Mutex{}
+		l.locks[networkID] = lock
+	}
+	l.mutex.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+func resourceIPAllocation() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Allocate the next free address of a network's assignment pools to a member. The address is added to the member's IP assignments, which is what reserves it, and removed again on destroy. Don't also list it in the `ip_assignments` of a `zerotier_member` resource for the same member.",
+		CreateContext: resourceIPAllocationCreate,
+		ReadContext:   resourceIPAllocationRead,
+		DeleteContext: resourceIPAllocationDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceIPAllocationImport,
+		},
+		Schema: map[string]*schema.Schema{
+			"network_id": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validNetworkID,
+				Description:      "ID of the network to allocate from.",
+			},
+			"member_id": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validMemberID,
+				Description:      "ID of the member the address is assigned to. The member must exist.",
+			},
+			"family": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				Default:          "ipv4",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"ipv4", "ipv6"}, false)),
+				Description:      "Which assignment pools to allocate from: `ipv4` or `ipv6`.",
+			},
+			"keepers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Arbitrary values that, when changed, release the address and allocate a new one. Otherwise the address stays the same across applies.",
+			},
+			"ip_address": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The allocated address.",
+			},
+		},
+	}
+}
+
+//
+// CRUD
+//
+
+func resourceIPAllocationCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	c := m.(backend)
+
+	nwid := d.Get("network_id").(string)
+	nodeID := d.Get("member_id").(string)
+
+	addr, err := allocateIP(ctx, c, nwid, nodeID, d.Get("family").(string) == "ipv6")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(ipAllocationIdString(nwid, nodeID, addr))
+	d.Set("ip_address", addr.String())
+
+	return nil
+}
+
+func resourceIPAllocationRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	c := m.(backend)
+
+	nwid := d.Get("network_id").(string)
+	nodeID := d.Get("member_id").(string)
+	ip := d.Get("ip_address").(string)
+
+	member, err := c.GetMember(ctx, nwid, nodeID)
+	if isNotFound(err) {
+		logrus.Warnf("ZeroTier Member %s of network %s no longer exists; removing its address %s from state", nodeID, nwid, ip)
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !memberHasIP(member, ip) {
+		logrus.Warnf("Address %s is no longer assigned to ZeroTier Member %s of network %s; removing it from state", ip, nodeID, nwid)
+		d.SetId("")
+		return nil
+	}
+
+	return nil
+}
+
+func resourceIPAllocationDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	c := m.(backend)
+
+	if err := releaseIP(ctx, c, d.Get("network_id").(string), d.Get("member_id").(string), d.Get("ip_address").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+func resourceIPAllocationImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.SplitN(d.Id(), "/", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("unable to parse allocation ID %q, expected network_id/member_id/ip_address", d.Id())
+	}
+
+	addr, err := netip.ParseAddr(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse allocation ID %q: %q is not an IP address", d.Id(), parts[2])
+	}
+
+	family := "ipv4"
+	if !addr.Is4() {
+		family = "ipv6"
+	}
+
+	d.Set("network_id", parts[0])
+	d.Set("member_id", parts[1])
+	d.Set("family", family)
+	d.Set("ip_address", addr.String())
+	d.SetId(ipAllocationIdString(parts[0], parts[1], addr))
+
+	return []*schema.ResourceData{d}, nil
+}
+
+//
+// allocation
+//
+
+// allocateIP assigns the first address of the network's pools that no
+// member has to the member nodeID.
+func allocateIP(ctx context.Context, c backend, nwid, nodeID string, ipv6 bool) (netip.Addr, error) {
+	defer allocationLocks.lock(nwid)()
+
+	n, err := c.GetNetwork(ctx, nwid)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	pools := []spec.IPRange{}
+	if n.Config != nil && n.Config.IpAssignmentPools != nil {
+		pools = *n.Config.IpAssignmentPools
+	}
+
+	members, err := c.GetMembers(ctx, nwid)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	var member *spec.Member
+	used := map[netip.Addr]bool{}
+
+	for _, mem := range members {
+		if ptrString(mem.NodeId) == nodeID {
+			member = mem
+		}
+
+		if mem.Config == nil || mem.Config.IpAssignments == nil {
+			continue
+		}
+
+		for _, ip := range *mem.Config.IpAssignments {
+			if addr, err := netip.ParseAddr(ip); err == nil {
+				used[addr] = true
+			}
+		}
+	}
+
+	if member == nil {
+		return netip.Addr{}, fmt.Errorf("member %s of network %s does not exist", nodeID, nwid)
+	}
+
+	addr, err := nextFreeIP(pools, used, ipv6)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("network %s: %w", nwid, err)
+	}
+
+	ips := []string{}
+	if member.Config != nil && member.Config.IpAssignments != nil {
+		ips = append(ips, *member.Config.IpAssignments...)
+	}
+	ips = append(ips, addr.String())
+
+	if _, err := c.UpdateMember(ctx, nwid, nodeID, &spec.Member{Config: &spec.MemberConfig{IpAssignments: &ips}}); err != nil {
+		return netip.Addr{}, err
+	}
+
+	return addr, nil
+}
+
+// releaseIP removes ip from the assignments of the member nodeID, if it is
+// still there.
+func releaseIP(ctx context.Context, c backend, nwid, nodeID, ip string) error {
+	defer allocationLocks.lock(nwid)()
+
+	member, err := c.GetMember(ctx, nwid, nodeID)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !memberHasIP(member, ip) {
+		return nil
+	}
+
+	ips := []string{}
+	for _, assigned := range *member.Config.IpAssignments {
+		if !sameIP(assigned, ip) {
+			ips = append(ips, assigned)
+		}
+	}
+
+	_, err = c.UpdateMember(ctx, nwid, nodeID, &spec.Member{Config: &spec.MemberConfig{IpAssignments: &ips}})
+	return err
+}
+
+// nextFreeIP returns the first address of pools, in their order, that is
+// not used and is in the requested address family.
+func nextFreeIP(pools []spec.IPRange, used map[netip.Addr]bool, ipv6 bool) (netip.Addr, error) {
+	for _, pool := range pools {
+		start, err := netip.ParseAddr(ptrString(pool.IpRangeStart))
+		if err != nil {
+			continue
+		}
+
+		end, err := netip.ParseAddr(ptrString(pool.IpRangeEnd))
+		if err != nil || start.Is4() == ipv6 || end.Is4() == ipv6 {
+			continue
+		}
+
+		// at most len(used) addresses are skipped, so this stays short
+		// even for large IPv6 pools.
+		for addr := start; addr.IsValid() && addr.Compare(end) <= 0; addr = addr.Next() {
+			if !used[addr] {
+				return addr, nil
+			}
+		}
+	}
+
+	family := "IPv4"
+	if ipv6 {
+		family = "IPv6"
+	}
+
+	return netip.Addr{}, fmt.Errorf("no free %s address left in the assignment pools", family)
+}
+
+func memberHasIP(member *spec.Member, ip string) bool {
+	if member.Config == nil || member.Config.IpAssignments == nil {
+		return false
+	}
+
+	for _, assigned := range *member.Config.IpAssignments {
+		if sameIP(assigned, ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameIP compares addresses regardless of how they are written.
+func sameIP(a, b string) bool {
+	addrA, errA := netip.ParseAddr(a)
+	addrB, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	return addrA == addrB
+}
+
+func ipAllocationIdString(nwid, nodeID string, addr netip.Addr) string {
+	return nwid + "/" + nodeID + "/" + addr.String()
+}
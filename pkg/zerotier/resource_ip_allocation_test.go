@@ -0,0 +1,335 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
+)
+
+func TestNextFreeIP(t *testing.T) {
+	pool := func(start, end string) spec.IPRange {
+		return spec.IPRange{IpRangeStart: stringPtr(start), IpRangeEnd: stringPtr(end)}
+	}
+
+	used := func(ips ...string) map[netip.Addr]bool {
+		res := map[netip.Addr]bool{}
+		for _, ip := range ips {
+			res[netip.MustParseAddr(ip)] = true
+		}
+		return res
+	}
+
+	tests := map[string]struct {
+		pools    []spec.IPRange
+		used     map[netip.Addr]bool
+		ipv6     bool
+		expected string
+		err      string
+	}{
+		"first": {
+			pools:    []spec.IPRange{pool("10.0.0.10", "10.0.0.20")},
+			expected: "10.0.0.10",
+		},
+		"skips used": {
+			pools:    []spec.IPRange{pool("10.0.0.10", "10.0.0.20")},
+			used:     used("10.0.0.10", "10.0.0.11", "10.0.0.13"),
+			expected: "10.0.0.12",
+		},
+		"next pool": {
+			pools:    []spec.IPRange{pool("10.0.0.10", "10.0.0.11"), pool("10.0.1.10", "10.0.1.20")},
+			used:     used("10.0.0.10", "10.0.0.11"),
+			expected: "10.0.1.10",
+		},
+		"across octets": {
+			pools:    []spec.IPRange{pool("10.0.0.255", "10.0.1.255")},
+			used:     used("10.0.0.255"),
+			expected: "10.0.1.0",
+		},
+		"ipv6": {
+			pools:    []spec.IPRange{pool("10.0.0.10", "10.0.0.20"), pool("fd00::10", "fd00::ffff:ffff:ffff:ffff")},
+			used:     used("fd00::10"),
+			ipv6:     true,
+			expected: "fd00::11",
+		},
+		"full": {
+			pools: []spec.IPRange{pool("10.0.0.10", "10.0.0.11")},
+			used:  used("10.0.0.10", "10.0.0.11"),
+			err:   "no free IPv4 address left in the assignment pools",
+		},
+		"no pool of the family": {
+			pools: []spec.IPRange{pool("10.0.0.10", "10.0.0.20")},
+			ipv6:  true,
+			err:   "no free IPv6 address left in the assignment pools",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			addr, err := nextFreeIP(test.pools, test.used, test.ipv6)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, addr.String())
+		})
+	}
+}
+
+func TestAllocateIP_Concurrent(t *testing.T) {
+	srv, c, nwid, ids := testMemberCache(t, 20)
+	srv.SetLatency(5 * time.Millisecond)
+
+	srv.SetNetwork(nwid, &spec.Network{Config: &spec.NetworkConfig{
+		IpAssignmentPools: &[]spec.IPRange{
+			{IpRangeStart: stringPtr("10.0.0.1"), IpRangeEnd: stringPtr("10.0.0.30")},
+		},
+	}})
+
+	// a hand-picked address is never allocated.
+	srv.SetMember(nwid, ids[0], &spec.Member{Config: &spec.MemberConfig{IpAssignments: &[]string{"10.0.0.1"}}})
+
+	var wg sync.WaitGroup
+	allocated := make([]string, len(ids))
+
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+
+			addr, err := allocateIP(context.Background(), c, nwid, id, false)
+			assert.NoError(t, err)
+			allocated[i] = addr.String()
+		}(i, id)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{"10.0.0.1": true}
+	for i, id := range ids {
+		assert.False(t, seen[allocated[i]], "%s was allocated twice", allocated[i])
+		seen[allocated[i]] = true
+
+		assert.Contains(t, *srv.Member(nwid, id).Config.IpAssignments, allocated[i])
+	}
+
+	assert.NoError(t, releaseIP(context.Background(), c, nwid, ids[0], allocated[0]))
+	assert.Equal(t, []string{"10.0.0.1"}, *srv.Member(nwid, ids[0]).Config.IpAssignments)
+
+	// the released address is the first free one again.
+	addr, err := allocateIP(context.Background(), c, nwid, ids[1], false)
+	assert.NoError(t, err)
+	assert.Equal(t, allocated[0], addr.String())
+
+	_, err = allocateIP(context.Background(), c, nwid, "ffffffffff", false)
+	assert.EqualError(t, err, fmt.Sprintf("member ffffffffff of network %s does not exist", nwid))
+}
+
+// testAccCheckAllocations checks that the allocations are distinct and
+// assigned to their members in central.
+func testAccCheckAllocations(srv *fakecentral.Server, count int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		seen := []string{}
+
+		for _, rs := range s.RootModule().Resources {
+			if rs.Type != "zerotier_ip_allocation" {
+				continue
+			}
+
+			ip := rs.Primary.Attributes["ip_address"]
+			member := srv.Member(rs.Primary.Attributes["network_id"], rs.Primary.Attributes["member_id"])
+			if member == nil || !memberHasIP(member, ip) {
+				return fmt.Errorf("%s is not assigned to member %s", ip, rs.Primary.Attributes["member_id"])
+			}
+
+			seen = append(seen, ip)
+		}
+
+		sort.Strings(seen)
+		for i := 1; i < len(seen); i++ {
+			if seen[i] == seen[i-1] {
+				return fmt.Errorf("%s was allocated twice", seen[i])
+			}
+		}
+
+		if len(seen) != count {
+			return fmt.Errorf("expected %d allocations, got %d", count, len(seen))
+		}
+
+		return nil
+	}
+}
+
+func TestAccIPAllocation_basic(t *testing.T) {
+	srv := testAccCentral(t)
+
+	config := func(keeper string) string {
+		return testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_network" "test" {
+  name = "acc-ip-allocation"
+
+  subnet {
+    cidr     = "10.0.0.0/24"
+    reserved = 9
+  }
+}
+
+resource "zerotier_member" "test" {
+  count      = 8
+  network_id = zerotier_network.test.id
+  member_id  = format("a1b2c3d4%%02x", count.index)
+}
+
+resource "zerotier_member" "static" {
+  network_id     = zerotier_network.test.id
+  member_id      = "ffffffffff"
+  ip_assignments = ["10.0.0.10"]
+}
+
+resource "zerotier_ip_allocation" "test" {
+  count      = 8
+  network_id = zerotier_network.test.id
+  member_id  = zerotier_member.test[count.index].member_id
+
+  keepers = {
+    generation = count.index == 0 ? %q : "1"
+  }
+
+  depends_on = [zerotier_member.static]
+}
+`, keeper))
+	}
+
+	var first string
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: config("1"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckAllocations(srv, 8),
+					func(s *terraform.State) error {
+						first = s.RootModule().Resources["zerotier_ip_allocation.test.0"].Primary.Attributes["ip_address"]
+						if first == "10.0.0.10" {
+							return fmt.Errorf("the static address was allocated")
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// the allocations stay put when nothing changed, and the members
+				// don't try to remove them.
+				Config:   config("1"),
+				PlanOnly: true,
+			},
+			{
+				Config: config("2"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckAllocations(srv, 8),
+					func(s *terraform.State) error {
+						id := s.RootModule().Resources["zerotier_member.test.0"].Primary.Attributes["member_id"]
+						nwid := s.RootModule().Resources["zerotier_network.test"].Primary.ID
+
+						if ips := *srv.Member(nwid, id).Config.IpAssignments; len(ips) != 1 {
+							return fmt.Errorf("expected the old address to be released, got %v", ips)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccIPAllocation_import(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-ip-allocation"
+
+  subnet {
+    cidr = "fd00::/64"
+  }
+}
+
+resource "zerotier_member" "test" {
+  network_id = zerotier_network.test.id
+  member_id  = "a1b2c3d4e5"
+}
+
+resource "zerotier_ip_allocation" "test" {
+  network_id = zerotier_network.test.id
+  member_id  = zerotier_member.test.member_id
+  family     = "ipv6"
+}
+`),
+				Check: resource.TestCheckResourceAttr("zerotier_ip_allocation.test", "ip_address", "fd00::1"),
+			},
+			{
+				ResourceName:      "zerotier_ip_allocation.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccIPAllocation_exhausted(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-ip-allocation"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+
+  assignment_pool {
+    start = "10.0.0.10"
+    end   = "10.0.0.11"
+  }
+}
+
+resource "zerotier_member" "test" {
+  count      = 3
+  network_id = zerotier_network.test.id
+  member_id  = format("a1b2c3d4%02x", count.index)
+}
+
+resource "zerotier_ip_allocation" "test" {
+  count      = 3
+  network_id = zerotier_network.test.id
+  member_id  = zerotier_member.test[count.index].member_id
+}
+`),
+				ExpectError: regexp.MustCompile(`no free IPv4 address left in the assignment pools`),
+			},
+		},
+	})
+}
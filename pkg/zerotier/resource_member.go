@@ -7,10 +7,15 @@ import (
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/zerotier/go-ztcentral"
+	"github.com/sirupsen/logrus"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
 )
 
+// memberDestroyModes are the values of on_destroy.
+var memberDestroyModes = []string{"delete", "deauthorize", "hide"}
+
 func resourceMember() *schema.Resource {
 	return &schema.Resource{
 		Description:   "Manage ZeroTier members and join them to networks",
@@ -18,19 +23,86 @@ func resourceMember() *schema.Resource {
 		ReadContext:   resourceMemberRead,
 		UpdateContext: resourceMemberUpdate,
 		DeleteContext: resourceMemberDelete,
-		Schema:        buildMemberSchema(true),
+		CustomizeDiff: customdiff.Sequence(
+			resourceMemberCustomizeRuleNames,
+			resourceMemberCustomizeAddresses,
+			resourceMemberCustomizeDestroyMode,
+		),
+		Schema: buildMemberSchema(true),
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultMemberCreateTimeout),
+		},
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			StateContext: resourceMemberImport,
+		},
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceMemberV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceMemberStateUpgradeV0,
+			},
 		},
 	}
 }
 
+// resourceMemberV0 is the part of the version 0 schema the upgrade to
+// version 1 depends on.
+func resourceMemberV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"network_id": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"member_id": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+		},
+	}
+}
+
+// resourceMemberStateUpgradeV0 rewrites the ID of the member, which may have
+// been either nwid-nodeid or nwid/nodeid, to the canonical nwid/nodeid.
+func resourceMemberStateUpgradeV0(ctx context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	nwid, _ := rawState["network_id"].(string)
+	nodeID, _ := rawState["member_id"].(string)
+
+	if nwid == "" || nodeID == "" {
+		id, _ := rawState["id"].(string)
+
+		var err error
+		nwid, nodeID, err = parseMemberId(id)
+		if err != nil {
+			return nil, err
+		}
+
+		rawState["network_id"] = nwid
+		rawState["member_id"] = nodeID
+	}
+
+	rawState["id"] = memberIdString(nwid, nodeID)
+
+	return rawState, nil
+}
+
+// resourceMemberCustomizeDestroyMode refuses to hide members on destroy when
+// the backend has no hidden members, rather than failing the destroy.
+func resourceMemberCustomizeDestroyMode(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Get("on_destroy").(string) == "hide" && isLocalBackend(m.(backend)) {
+		return errors.New(`on_destroy: hide is only offered by ZeroTier Central, not a local controller; use "deauthorize" or "delete"`)
+	}
+
+	return nil
+}
+
 //
 // CRUD
 //
 
 func resourceMemberRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	c := m.(*ztcentral.Client)
+	c := m.(backend)
 
 	nwid, nodeId, err := resourceNetworkAndNodeIdentifiers(d)
 	if err != nil {
@@ -38,6 +110,11 @@ func resourceMemberRead(ctx context.Context, d *schema.ResourceData, m interface
 	}
 
 	member, err := c.GetMember(ctx, nwid, nodeId)
+	if isNotFound(err) {
+		logrus.Warnf("ZeroTier Member %s of network %s no longer exists; removing it from state", nodeId, nwid)
+		d.SetId("")
+		return nil
+	}
 	if err != nil {
 		return diag.FromErr(err)
 	}
@@ -47,7 +124,15 @@ func resourceMemberRead(ctx context.Context, d *schema.ResourceData, m interface
 
 func resourceMemberCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	member := toMember(d)
-	c := m.(*ztcentral.Client)
+	c := m.(backend)
+
+	if err := applyMemberRuleNames(ctx, c, d, member); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if diags := checkMemberAddresses(ctx, c, member); diags.HasError() {
+		return diags
+	}
 
 	_, err := c.CreateAuthorizedMember(ctx, *member.NetworkId, *member.NodeId, *member.Name)
 	if err != nil {
@@ -59,14 +144,38 @@ func resourceMemberCreate(ctx context.Context, d *schema.ResourceData, m interfa
 		return diag.FromErr(err)
 	}
 
+	// the member exists from here on, even if waiting for it fails.
+	d.SetId(memberIdString(*member.NetworkId, *member.NodeId))
+
+	res, diags := waitForMember(ctx, c, d, res)
+	if diags.HasError() {
+		return diags
+	}
+
 	return memberToTerraform(d, res)
 }
 
 func resourceMemberUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	c := m.(*ztcentral.Client)
+	c := m.(backend)
 
 	member := toMember(d)
 
+	// nothing is written until the checks pass, so a failed check keeps the
+	// previous state rather than the planned one.
+	d.Partial(true)
+
+	if err := applyMemberRuleNames(ctx, c, d, member); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if d.HasChange("ip_assignments") {
+		if diags := checkMemberAddresses(ctx, c, member); diags.HasError() {
+			return diags
+		}
+	}
+
+	d.Partial(false)
+
 	updated, err := c.UpdateMember(ctx, *member.NetworkId, *member.NodeId, member)
 	if err != nil {
 		return diag.FromErr(err)
@@ -76,17 +185,73 @@ func resourceMemberUpdate(ctx context.Context, d *schema.ResourceData, m interfa
 }
 
 func resourceMemberDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	c := m.(*ztcentral.Client)
+	c := m.(backend)
 	member := toMember(d)
 
-	if err := c.DeleteMember(ctx, *member.NetworkId, *member.NodeId); err != nil {
-		return diag.FromErr(err)
+	if diags := checkDeletionProtection(d, "Member", d.Id()); diags.HasError() {
+		return diags
+	}
+
+	switch d.Get("on_destroy").(string) {
+	case "deauthorize":
+		if err := keepMember(ctx, c, member, &spec.Member{Config: &spec.MemberConfig{Authorized: boolPtr(false)}}); err != nil {
+			return diag.FromErr(err)
+		}
+	case "hide":
+		err := keepMember(ctx, c, member, &spec.Member{Hidden: boolPtr(true), Config: &spec.MemberConfig{Authorized: boolPtr(false)}})
+		if errors.Is(err, errUnsupported) {
+			return diag.Diagnostics{{
+				Severity: diag.Error,
+				Summary:  "Unable to hide ZeroTier Member",
+				Detail:   fmt.Sprintf("Member %s can't be hidden, as the configured controller backend has no hidden members. Set on_destroy to \"deauthorize\" or \"delete\" and apply before destroying it.", d.Id()),
+			}}
+		}
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	default:
+		if err := c.DeleteMember(ctx, *member.NetworkId, *member.NodeId); err != nil {
+			return diag.FromErr(err)
+		}
 	}
 
 	d.SetId("")
 	return nil
 }
 
+// keepMember applies update to member instead of deleting it. A member that
+// is already gone stays gone; posting to it would create it again.
+func keepMember(ctx context.Context, c backend, member, update *spec.Member) error {
+	_, err := c.GetMember(ctx, *member.NetworkId, *member.NodeId)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = c.UpdateMember(ctx, *member.NetworkId, *member.NodeId, update)
+	return err
+}
+
+func resourceMemberImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	nwid, nodeID, err := parseMemberId(d.Id())
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("network_id", nwid)
+	d.Set("member_id", nodeID)
+	d.Set("deletion_protection", false)
+	d.Set("on_destroy", "delete")
+	d.Set("online_threshold", defaultOnlineThreshold)
+	d.Set("wait_for_online", false)
+	d.Set("wait_for_ip_assignment", false)
+	d.SetId(memberIdString(nwid, nodeID))
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceNetworkAndNodeIdentifiers(d *schema.ResourceData) (string, string, error) {
 	nwid := d.Get("network_id").(string)
 	nodeID := d.Get("member_id").(string)
@@ -101,15 +266,37 @@ func resourceNetworkAndNodeIdentifiers(d *schema.ResourceData) (string, string,
 	return nwid, nodeID, nil
 }
 
+// parseMemberId splits a member ID in either the nwid/nodeid form used in
+// state or the older nwid-nodeid, and checks both halves look like ZeroTier
+// IDs.
 func parseMemberId(id string) (string, string, error) {
-	parts := strings.SplitN(id, "-", 2)
+	sep := "/"
+	if !strings.Contains(id, sep) {
+		sep = "-"
+	}
+
+	parts := strings.SplitN(id, sep, 2)
 
 	if len(parts) != 2 {
-		return "", "", errors.New(fmt.Sprintf("invalid format: '%s' (wrong syntax)", id))
+		return "", "", fmt.Errorf("invalid format: '%s' (wrong syntax, expected <network_id>/<member_id>)", id)
 	}
 	if parts[0] == "" || parts[1] == "" {
-		return "", "", errors.New(fmt.Sprintf("invalid format: '%s' (all components are required)", id))
+		return "", "", fmt.Errorf("invalid format: '%s' (all components are required)", id)
+	}
+
+	nwid, nodeID := strings.ToLower(parts[0]), strings.ToLower(parts[1])
+
+	if !networkIDRegexp.MatchString(nwid) {
+		return "", "", fmt.Errorf("invalid format: '%s' (network ID must be 16 hexadecimal characters)", id)
 	}
+	if !memberIDRegexp.MatchString(nodeID) {
+		return "", "", fmt.Errorf("invalid format: '%s' (member ID must be 10 hexadecimal characters)", id)
+	}
+
+	return nwid, nodeID, nil
+}
 
-	return parts[0], parts[1], nil
+// memberIdString is the canonical ID of a member in state.
+func memberIdString(nwid, nodeID string) string {
+	return nwid + "/" + nodeID
 }
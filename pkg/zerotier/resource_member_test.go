@@ -1,10 +1,17 @@
 package zerotier
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
 )
 
 func Test_ResourceNetworkAndNodeIdentifiers_PresetValues(t *testing.T) {
@@ -68,8 +75,38 @@ func Test_ResourceNetworkAndNodeIdentifiers_ParsingMemberID(t *testing.T) {
 		},
 		{
 			desc:              "Wellformed",
-			inputId:           "0xcafe-0xbaby",
-			expectedNetworkID: "0xcafe", expectedNodeId: "0xbaby",
+			inputId:           "8056c2e21c000001/a1b2c3d4e5",
+			expectedNetworkID: "8056c2e21c000001", expectedNodeId: "a1b2c3d4e5",
+		},
+		{
+			desc:              "Wellformed with a dash",
+			inputId:           "8056c2e21c000001-a1b2c3d4e5",
+			expectedNetworkID: "8056c2e21c000001", expectedNodeId: "a1b2c3d4e5",
+		},
+		{
+			desc:              "Upper case",
+			inputId:           "8056C2E21C000001/A1B2C3D4E5",
+			expectedNetworkID: "8056c2e21c000001", expectedNodeId: "a1b2c3d4e5",
+		},
+		{
+			desc:               "Short network id",
+			inputId:            "8056c2e21c/a1b2c3d4e5",
+			expectedErrPattern: "invalid format.*(network ID must be 16 hexadecimal characters)",
+		},
+		{
+			desc:               "Long member id",
+			inputId:            "8056c2e21c000001-a1b2c3d4e5f6",
+			expectedErrPattern: "invalid format.*(member ID must be 10 hexadecimal characters)",
+		},
+		{
+			desc:               "Not hexadecimal",
+			inputId:            "8056c2e21c000001/a1b2c3d4zz",
+			expectedErrPattern: "invalid format.*(member ID must be 10 hexadecimal characters)",
+		},
+		{
+			desc:               "Too many components",
+			inputId:            "8056c2e21c000001/a1b2c3d4e5/a1b2c3d4e5",
+			expectedErrPattern: "invalid format.*(member ID must be 10 hexadecimal characters)",
 		},
 	}
 
@@ -93,3 +130,419 @@ func Test_ResourceNetworkAndNodeIdentifiers_ParsingMemberID(t *testing.T) {
 		})
 	}
 }
+
+func TestResourceMemberImport(t *testing.T) {
+	for _, id := range []string{
+		"8056c2e21c000001/a1b2c3d4e5",
+		"8056c2e21c000001-a1b2c3d4e5",
+		"8056C2E21C000001-A1B2C3D4E5",
+	} {
+		t.Run(id, func(t *testing.T) {
+			d := resourceMember().TestResourceData()
+			d.SetId(id)
+
+			res, err := resourceMemberImport(context.Background(), d, nil)
+			assert.NoError(t, err)
+			assert.Len(t, res, 1)
+			assert.Equal(t, "8056c2e21c000001/a1b2c3d4e5", res[0].Id())
+			assert.Equal(t, "8056c2e21c000001", res[0].Get("network_id"))
+			assert.Equal(t, "a1b2c3d4e5", res[0].Get("member_id"))
+		})
+	}
+
+	d := resourceMember().TestResourceData()
+	d.SetId("a1b2c3d4e5")
+
+	_, err := resourceMemberImport(context.Background(), d, nil)
+	assert.ErrorContains(t, err, "expected <network_id>/<member_id>")
+}
+
+func TestResourceMemberStateUpgradeV0(t *testing.T) {
+	tests := []struct {
+		desc     string
+		rawState map[string]interface{}
+
+		expectedErrPattern string
+		expectedState      map[string]interface{}
+	}{
+		{
+			desc: "Canonical",
+			rawState: map[string]interface{}{
+				"id": "8056c2e21c000001/a1b2c3d4e5", "network_id": "8056c2e21c000001", "member_id": "a1b2c3d4e5", "name": "alice",
+			},
+			expectedState: map[string]interface{}{
+				"id": "8056c2e21c000001/a1b2c3d4e5", "network_id": "8056c2e21c000001", "member_id": "a1b2c3d4e5", "name": "alice",
+			},
+		},
+		{
+			desc: "Dash",
+			rawState: map[string]interface{}{
+				"id": "8056c2e21c000001-a1b2c3d4e5", "network_id": "8056c2e21c000001", "member_id": "a1b2c3d4e5",
+			},
+			expectedState: map[string]interface{}{
+				"id": "8056c2e21c000001/a1b2c3d4e5", "network_id": "8056c2e21c000001", "member_id": "a1b2c3d4e5",
+			},
+		},
+		{
+			desc: "Only the ID",
+			rawState: map[string]interface{}{
+				"id": "8056c2e21c000001-a1b2c3d4e5",
+			},
+			expectedState: map[string]interface{}{
+				"id": "8056c2e21c000001/a1b2c3d4e5", "network_id": "8056c2e21c000001", "member_id": "a1b2c3d4e5",
+			},
+		},
+		{
+			desc: "Unparseable",
+			rawState: map[string]interface{}{
+				"id": "garbage",
+			},
+			expectedErrPattern: "invalid format",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			state, err := resourceMemberStateUpgradeV0(context.Background(), test.rawState, nil)
+
+			if test.expectedErrPattern != "" {
+				assert.Error(t, err)
+				assert.Regexp(t, test.expectedErrPattern, err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedState, state)
+		})
+	}
+}
+
+func testAccCheckMemberDestroyed(srv *fakecentral.Server) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		for _, rs := range s.RootModule().Resources {
+			if rs.Type != "zerotier_member" {
+				continue
+			}
+
+			if srv.Member(rs.Primary.Attributes["network_id"], rs.Primary.Attributes["member_id"]) != nil {
+				return fmt.Errorf("member %q still exists", rs.Primary.ID)
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestAccMember_basic(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-member"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+
+  route {
+    target = "fd00::/64"
+  }
+}
+
+resource "zerotier_member" "test" {
+  network_id              = zerotier_network.test.id
+  member_id               = "a1b2c3d4e5"
+  name                    = "alice"
+  description             = "Hello, world"
+  hidden                  = true
+  authorized              = false
+  allow_ethernet_bridging = true
+  no_auto_assign_ips      = true
+  sso_exempt              = true
+  ip_assignments          = ["10.0.0.1", "fd00::1"]
+  capabilities            = [1, 2, 3]
+  tags                    = [[1000, 100]]
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair("zerotier_member.test", "network_id", "zerotier_network.test", "id"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "member_id", "a1b2c3d4e5"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "name", "alice"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "description", "Hello, world"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "hidden", "true"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "authorized", "false"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "allow_ethernet_bridging", "true"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "no_auto_assign_ips", "true"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "sso_exempt", "true"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "ip_assignments.#", "2"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "ipv4_assignments.#", "1"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "ipv6_assignments.#", "1"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "capabilities.#", "3"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "tags.#", "1"),
+					resource.TestCheckResourceAttrSet("zerotier_member.test", "rfc4193"),
+					resource.TestCheckResourceAttrSet("zerotier_member.test", "sixplane"),
+				),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-member"
+}
+
+resource "zerotier_member" "test" {
+  network_id = zerotier_network.test.id
+  member_id  = "a1b2c3d4e5"
+  name       = "alice"
+  authorized = true
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_member.test", "authorized", "true"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "hidden", "false"),
+					resource.TestCheckResourceAttr("zerotier_member.test", "description", "Managed by Terraform"),
+				),
+			},
+			{
+				ResourceName:      "zerotier_member.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				ResourceName: "zerotier_member.test",
+				ImportState:  true,
+				ImportStateIdFunc: func(s *terraform.State) (string, error) {
+					rs := s.RootModule().Resources["zerotier_member.test"]
+					return rs.Primary.Attributes["network_id"] + "-" + rs.Primary.Attributes["member_id"], nil
+				},
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccMember_disappears(t *testing.T) {
+	srv := testAccCentral(t)
+	var nwid string
+
+	config := testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-member-disappears"
+}
+
+resource "zerotier_member" "test" {
+  network_id = zerotier_network.test.id
+  member_id  = "a1b2c3d4e5"
+  name       = "alice"
+}
+`)
+
+	checkRecreated := func(s *terraform.State) error {
+		nwid = s.RootModule().Resources["zerotier_network.test"].Primary.ID
+		if srv.Member(nwid, "a1b2c3d4e5") == nil {
+			return fmt.Errorf("member was not recreated")
+		}
+
+		return nil
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckMemberDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check:  checkRecreated,
+			},
+			{
+				PreConfig: func() { srv.DeleteMember(nwid, "a1b2c3d4e5") },
+				Config:    config,
+				Check:     checkRecreated,
+			},
+			{
+				PreConfig: func() { srv.DeleteNetwork(nwid) },
+				Config:    config,
+				Check:     checkRecreated,
+			},
+		},
+	})
+}
+
+func TestResourceMemberRead_Errors(t *testing.T) {
+	srv := testAccCentral(t)
+
+	c, err := newCentralBackend(srv.URL(), srv.Token, http.DefaultTransport)
+	assert.NoError(t, err)
+
+	n, err := c.NewNetwork(context.Background(), "errors", &spec.Network{})
+	assert.NoError(t, err)
+
+	_, err = c.CreateAuthorizedMember(context.Background(), *n.Id, "a1b2c3d4e5", "alice")
+	assert.NoError(t, err)
+
+	unauthorized, err := newCentralBackend(srv.URL(), "wrong", http.DefaultTransport)
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		desc     string
+		backend  backend
+		nwid     string
+		memberID string
+		fail     int
+
+		expectedErr     string
+		expectedCleared bool
+	}{
+		{
+			desc:     "Found",
+			backend:  c,
+			nwid:     *n.Id,
+			memberID: "a1b2c3d4e5",
+		},
+		{
+			desc:            "Member not found",
+			backend:         c,
+			nwid:            *n.Id,
+			memberID:        "ffffffffff",
+			expectedCleared: true,
+		},
+		{
+			desc:            "Network not found",
+			backend:         newMemberCache(c),
+			nwid:            "0123456789abcdef",
+			memberID:        "a1b2c3d4e5",
+			expectedCleared: true,
+		},
+		{
+			desc:        "Unauthorized",
+			backend:     unauthorized,
+			nwid:        *n.Id,
+			memberID:    "a1b2c3d4e5",
+			expectedErr: "401",
+		},
+		{
+			desc:        "Server error",
+			backend:     newMemberCache(c),
+			nwid:        *n.Id,
+			memberID:    "a1b2c3d4e5",
+			fail:        http.StatusBadGateway,
+			expectedErr: "502",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if test.fail != 0 {
+				srv.FailNext(1, test.fail, "")
+			}
+
+			d := resourceMember().TestResourceData()
+			d.SetId(test.nwid + "/" + test.memberID)
+			d.Set("network_id", test.nwid)
+			d.Set("member_id", test.memberID)
+
+			diags := resourceMemberRead(context.Background(), d, test.backend)
+			if test.expectedErr != "" {
+				assert.True(t, diags.HasError())
+				assert.Contains(t, diags[0].Summary, test.expectedErr)
+			} else {
+				assert.False(t, diags.HasError())
+			}
+
+			assert.Equal(t, test.expectedCleared, d.Id() == "")
+		})
+	}
+}
+
+func TestAccMember_onDestroy(t *testing.T) {
+	tests := map[string]struct {
+		check func(member *spec.Member) error
+	}{
+		"delete": {
+			check: func(member *spec.Member) error {
+				if member != nil {
+					return fmt.Errorf("member still exists")
+				}
+				return nil
+			},
+		},
+		"deauthorize": {
+			check: func(member *spec.Member) error {
+				if member == nil || ptrBool(member.Config.Authorized) || ptrBool(member.Hidden) {
+					return fmt.Errorf("expected a kept, deauthorized and visible member, got %+v", member)
+				}
+				return nil
+			},
+		},
+		"hide": {
+			check: func(member *spec.Member) error {
+				if member == nil || ptrBool(member.Config.Authorized) || !ptrBool(member.Hidden) {
+					return fmt.Errorf("expected a kept, deauthorized and hidden member, got %+v", member)
+				}
+				return nil
+			},
+		},
+	}
+
+	for mode, test := range tests {
+		t.Run(mode, func(t *testing.T) {
+			srv := testAccCentral(t)
+
+			// the network outlives the test, so the member's record can be
+			// checked after it is destroyed.
+			c, err := newCentralBackend(srv.URL(), srv.Token, http.DefaultTransport)
+			assert.NoError(t, err)
+
+			n, err := c.NewNetwork(context.Background(), "acc-member-on-destroy", &spec.Network{})
+			assert.NoError(t, err)
+
+			resource.Test(t, resource.TestCase{
+				ProviderFactories: testAccProviderFactories,
+				CheckDestroy: func(s *terraform.State) error {
+					member := srv.Member(*n.Id, "a1b2c3d4e5")
+					if err := test.check(member); err != nil {
+						return err
+					}
+
+					if member != nil && ptrString(member.Name) != "alice" {
+						return fmt.Errorf("the member's record was not kept: %+v", member)
+					}
+
+					return nil
+				},
+				Steps: []resource.TestStep{
+					{
+						Config: testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_member" "test" {
+  network_id = %q
+  member_id  = "a1b2c3d4e5"
+  name       = "alice"
+  on_destroy = %q
+}
+`, *n.Id, mode)),
+						Check: resource.ComposeTestCheckFunc(
+							resource.TestCheckResourceAttr("zerotier_member.test", "on_destroy", mode),
+							resource.TestCheckResourceAttr("zerotier_member.test", "authorized", "true"),
+						),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestKeepMember(t *testing.T) {
+	srv, c, nwid, ids := testMemberCache(t, 1)
+
+	update := &spec.Member{Config: &spec.MemberConfig{Authorized: boolPtr(false)}}
+
+	assert.NoError(t, keepMember(context.Background(), c, &spec.Member{NetworkId: &nwid, NodeId: &ids[0]}, update))
+	assert.False(t, ptrBool(srv.Member(nwid, ids[0]).Config.Authorized))
+
+	// a member deleted behind terraform's back is not brought back to be
+	// deauthorized.
+	assert.NoError(t, keepMember(context.Background(), c, &spec.Member{NetworkId: &nwid, NodeId: stringPtr("ffffffffff")}, update))
+	assert.Nil(t, srv.Member(nwid, "ffffffffff"))
+}
@@ -5,8 +5,9 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/zerotier/go-ztcentral"
+	"github.com/sirupsen/logrus"
 )
 
 func resourceNetwork() *schema.Resource {
@@ -16,15 +17,87 @@ func resourceNetwork() *schema.Resource {
 		ReadContext:   resourceNetworkRead,
 		UpdateContext: resourceNetworkUpdate,
 		DeleteContext: resourceNetworkDelete,
-		Schema:        NetworkSchema,
+		CustomizeDiff: customdiff.Sequence(
+			resourceNetworkCustomizeFlowRules,
+			resourceNetworkCustomizeFlowRuleNames,
+			resourceNetworkCustomizeSSO,
+			resourceNetworkCustomizeAddresses,
+		),
+		Schema: resourceNetworkSchema(),
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			StateContext: resourceNetworkImport,
+		},
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceNetworkV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceNetworkStateUpgradeV0,
+			},
+		},
+	}
+}
+
+// resourceNetworkV0 is the part of the version 0 schema the upgrade to
+// version 1 depends on.
+func resourceNetworkV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"dns": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"domain": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"servers": {
+							Type:     schema.TypeList,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Required: true,
+						},
+					},
+				},
+			},
 		},
 	}
 }
 
+// resourceNetworkStateUpgradeV0 collapses the dns set, which always held an
+// element even for a network without DNS and could hold several, of which
+// only the last was sent to central, into at most one block.
+func resourceNetworkStateUpgradeV0(ctx context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	blocks, _ := rawState["dns"].([]interface{})
+
+	dns := []interface{}{}
+	for _, block := range blocks {
+		m, ok := block.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		domain, _ := m["domain"].(string)
+		servers, _ := m["servers"].([]interface{})
+
+		if domain == "" && len(servers) == 0 {
+			continue
+		}
+
+		dns = []interface{}{map[string]interface{}{
+			"domain":  domain,
+			"servers": servers,
+		}}
+	}
+
+	rawState["dns"] = dns
+
+	return rawState, nil
+}
+
 func resourceNetworkCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	c := m.(*ztcentral.Client)
+	c := m.(backend)
 	net, derr := toNetwork(d)
 	if derr != nil {
 		return derr
@@ -39,7 +112,7 @@ func resourceNetworkCreate(ctx context.Context, d *schema.ResourceData, m interf
 		}}
 	}
 
-	rs, err := c.UpdateNetworkRules(ctx, *n.Id, d.Get("flow_rules").(string))
+	rs, err := c.UpdateNetworkRules(ctx, *n.Id, *net.RulesSource)
 	if err != nil {
 		return []diag.Diagnostic{{
 			Severity: diag.Error,
@@ -53,10 +126,15 @@ func resourceNetworkCreate(ctx context.Context, d *schema.ResourceData, m interf
 }
 
 func resourceNetworkRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	c := m.(*ztcentral.Client)
+	c := m.(backend)
 	var diags diag.Diagnostics
 
 	ztNetwork, err := c.GetNetwork(ctx, d.Get("id").(string))
+	if isNotFound(err) {
+		logrus.Warnf("ZeroTier Network %s no longer exists; removing it from state", d.Id())
+		d.SetId("")
+		return nil
+	}
 	if err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Error,
@@ -70,7 +148,7 @@ func resourceNetworkRead(ctx context.Context, d *schema.ResourceData, m interfac
 }
 
 func resourceNetworkUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	c := m.(*ztcentral.Client)
+	c := m.(backend)
 	net, derr := toNetwork(d)
 	if derr != nil {
 		return derr
@@ -81,7 +159,7 @@ func resourceNetworkUpdate(ctx context.Context, d *schema.ResourceData, m interf
 		return diag.FromErr(err)
 	}
 
-	rs, err := c.UpdateNetworkRules(ctx, *net.Id, d.Get("flow_rules").(string))
+	rs, err := c.UpdateNetworkRules(ctx, *net.Id, *net.RulesSource)
 	if err != nil {
 		return []diag.Diagnostic{{
 			Severity: diag.Error,
@@ -96,11 +174,19 @@ func resourceNetworkUpdate(ctx context.Context, d *schema.ResourceData, m interf
 }
 
 func resourceNetworkDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	c := m.(*ztcentral.Client)
+	c := m.(backend)
 	var diags diag.Diagnostics
 
 	networkID := d.Id()
 
+	if diags := checkDeletionProtection(d, "Network", networkID); diags.HasError() {
+		return diags
+	}
+
+	if diags := checkNoOnlineMembers(ctx, c, d, networkID); diags.HasError() {
+		return diags
+	}
+
 	err := c.DeleteNetwork(ctx, networkID)
 	if err != nil {
 		return diag.FromErr(err)
@@ -110,3 +196,10 @@ func resourceNetworkDelete(ctx context.Context, d *schema.ResourceData, m interf
 
 	return diags
 }
+
+func resourceNetworkImport(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	// settings that only live in terraform start out at their defaults.
+	d.Set("deletion_protection", false)
+
+	return []*schema.ResourceData{d}, nil
+}
@@ -0,0 +1,628 @@
+package zerotier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
+)
+
+func testAccCheckNetworkDestroyed(srv *fakecentral.Server) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		for _, rs := range s.RootModule().Resources {
+			if rs.Type != "zerotier_network" {
+				continue
+			}
+
+			if srv.Network(rs.Primary.ID) != nil {
+				return fmt.Errorf("network %q still exists", rs.Primary.ID)
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestAccNetwork_basic(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckNetworkDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name        = "acc-network"
+  description = "acceptance test"
+  flow_rules  = "drop;"
+
+  assignment_pool {
+    start = "10.0.0.1"
+    end   = "10.0.0.254"
+  }
+
+  route {
+    target = "10.0.0.0/24"
+  }
+
+  dns {
+    domain  = "acc.test"
+    servers = ["10.0.0.2", "10.0.0.3"]
+  }
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("zerotier_network.test", "id"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "name", "acc-network"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "description", "acceptance test"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "flow_rules", "drop;"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "private", "true"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "multicast_limit", "32"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "route.#", "1"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "assignment_pool.#", "1"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "dns.#", "1"),
+				),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name             = "acc-network-renamed"
+  description      = "acceptance test"
+  private          = false
+  enable_broadcast = false
+  multicast_limit  = 64
+  mtu              = 1400
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_network.test", "name", "acc-network-renamed"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "private", "false"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "enable_broadcast", "false"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "multicast_limit", "64"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "mtu", "1400"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "flow_rules", "accept;"),
+				),
+			},
+			{
+				ResourceName:      "zerotier_network.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccNetwork_disappears(t *testing.T) {
+	srv := testAccCentral(t)
+	var id string
+
+	config := testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-disappears"
+}
+`)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckNetworkDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: func(s *terraform.State) error {
+					id = s.RootModule().Resources["zerotier_network.test"].Primary.ID
+					return nil
+				},
+			},
+			{
+				PreConfig: func() { srv.DeleteNetwork(id) },
+				Config:    config,
+				Check: func(s *terraform.State) error {
+					recreated := s.RootModule().Resources["zerotier_network.test"].Primary.ID
+					if recreated == id || srv.Network(recreated) == nil {
+						return fmt.Errorf("network %q was not recreated", id)
+					}
+
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestResourceNetworkRead_Errors(t *testing.T) {
+	srv := testAccCentral(t)
+
+	c, err := newCentralBackend(srv.URL(), srv.Token, http.DefaultTransport)
+	assert.NoError(t, err)
+
+	n, err := c.NewNetwork(context.Background(), "errors", &spec.Network{})
+	assert.NoError(t, err)
+
+	unauthorized, err := newCentralBackend(srv.URL(), "wrong", http.DefaultTransport)
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		desc    string
+		backend backend
+		id      string
+		fail    int
+
+		expectedErr string
+		expectedID  string
+	}{
+		{
+			desc:       "Found",
+			backend:    c,
+			id:         *n.Id,
+			expectedID: *n.Id,
+		},
+		{
+			desc:    "Not found",
+			backend: c,
+			id:      "0123456789abcdef",
+		},
+		{
+			desc:        "Unauthorized",
+			backend:     unauthorized,
+			id:          *n.Id,
+			expectedErr: "401",
+			expectedID:  *n.Id,
+		},
+		{
+			desc:        "Server error",
+			backend:     c,
+			id:          *n.Id,
+			fail:        http.StatusInternalServerError,
+			expectedErr: "500",
+			expectedID:  *n.Id,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if test.fail != 0 {
+				srv.FailNext(1, test.fail, "")
+			}
+
+			d := resourceNetwork().TestResourceData()
+			d.SetId(test.id)
+
+			diags := resourceNetworkRead(context.Background(), d, test.backend)
+			if test.expectedErr != "" {
+				assert.True(t, diags.HasError())
+				assert.Contains(t, diags[0].Detail, test.expectedErr)
+			} else {
+				assert.False(t, diags.HasError())
+			}
+
+			assert.Equal(t, test.expectedID, d.Id())
+		})
+	}
+}
+
+func TestAccNetwork_invalidFlowRules(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name       = "acc-network-rules"
+  flow_rules = <<-EOT
+    drop
+      not ethertype ipv4
+      and not ethertpye ipv6
+    ;
+    accept;
+  EOT
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`line 3, column 11: unknown match "ethertpye"`),
+			},
+		},
+	})
+
+	assert.Empty(t, srv.NetworkIDs())
+}
+
+func TestNetwork_ValidateFlowRules(t *testing.T) {
+	path := cty.GetAttrPath("flow_rules")
+
+	assert.Empty(t, validFlowRules("accept;", path))
+
+	diags := validFlowRules("accept;\ndrop not chr tcp_sin;", path)
+	assert.True(t, diags.HasError())
+	assert.Equal(t, "Invalid flow rules", diags[0].Summary)
+	assert.Equal(t, `line 2, column 14: unknown characteristic "tcp_sin", expected one of broadcast, inbound, ipauth, macauth, multicast, tcp_ack, tcp_cwr, tcp_ece, tcp_fin, tcp_ns, tcp_psh, tcp_rs0, tcp_rs1, tcp_rs2, tcp_rst, tcp_syn, tcp_urg`, diags[0].Detail)
+	assert.Equal(t, path, diags[0].AttributePath)
+}
+
+func TestAccNetwork_sso(t *testing.T) {
+	srv := testAccCentral(t)
+
+	var id string
+
+	checkSSO := func(expected *spec.NetworkSSOConfig) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			id = s.RootModule().Resources["zerotier_network.test"].Primary.ID
+
+			actual := srv.Network(id).Config.SsoConfig
+			if !assert.ObjectsAreEqual(expected, actual) {
+				return fmt.Errorf("expected SSO config %+v, got %+v", expected, actual)
+			}
+
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckNetworkDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-sso"
+
+  sso {
+    issuer    = "https://issuer.example.com"
+    client_id = "terraform"
+  }
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					checkSSO(&spec.NetworkSSOConfig{
+						Enabled:               boolPtr(true),
+						Mode:                  stringPtr("default"),
+						Issuer:                stringPtr("https://issuer.example.com"),
+						ClientId:              stringPtr("terraform"),
+						AuthorizationEndpoint: stringPtr(""),
+						AllowList:             &[]string{},
+					}),
+					resource.TestCheckResourceAttr("zerotier_network.test", "sso.#", "1"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "sso.0.enabled", "true"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "sso.0.mode", "default"),
+				),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-sso"
+
+  sso {
+    mode                   = "email"
+    issuer                 = "https://issuer.example.com"
+    client_id              = "terraform"
+    authorization_endpoint = "https://issuer.example.com/authorize"
+    allowed_members        = ["bob@example.com", "alice@example.com"]
+  }
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					checkSSO(&spec.NetworkSSOConfig{
+						Enabled:               boolPtr(true),
+						Mode:                  stringPtr("email"),
+						Issuer:                stringPtr("https://issuer.example.com"),
+						ClientId:              stringPtr("terraform"),
+						AuthorizationEndpoint: stringPtr("https://issuer.example.com/authorize"),
+						AllowList:             &[]string{"alice@example.com", "bob@example.com"},
+					}),
+					resource.TestCheckResourceAttr("zerotier_network.test", "sso.0.mode", "email"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "sso.0.allowed_members.#", "2"),
+				),
+			},
+			{
+				// changed in the Central UI.
+				PreConfig: func() {
+					srv.SetNetwork(id, &spec.Network{Config: &spec.NetworkConfig{
+						SsoConfig: &spec.NetworkSSOConfig{AllowList: &[]string{"mallory@example.com"}},
+					}})
+				},
+				RefreshState:       true,
+				ExpectNonEmptyPlan: true,
+				Check:              resource.TestCheckResourceAttr("zerotier_network.test", "sso.0.allowed_members.#", "1"),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-sso"
+
+  sso {
+    enabled   = false
+    issuer    = "https://issuer.example.com"
+    client_id = "terraform"
+  }
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					checkSSO(&spec.NetworkSSOConfig{
+						Enabled:               boolPtr(false),
+						Mode:                  stringPtr("default"),
+						Issuer:                stringPtr("https://issuer.example.com"),
+						ClientId:              stringPtr("terraform"),
+						AuthorizationEndpoint: stringPtr("https://issuer.example.com/authorize"),
+						AllowList:             &[]string{},
+					}),
+					resource.TestCheckResourceAttr("zerotier_network.test", "sso.0.enabled", "false"),
+				),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-sso"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					checkSSO(&spec.NetworkSSOConfig{
+						Enabled:               boolPtr(false),
+						Mode:                  stringPtr("default"),
+						Issuer:                stringPtr(""),
+						ClientId:              stringPtr(""),
+						AuthorizationEndpoint: stringPtr(""),
+						AllowList:             &[]string{},
+					}),
+					resource.TestCheckResourceAttr("zerotier_network.test", "sso.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccNetwork_invalidSSO(t *testing.T) {
+	srv := testAccCentral(t)
+
+	tests := map[string]struct {
+		sso      string
+		private  bool
+		expected string
+	}{
+		"missing client_id": {
+			sso:      `issuer = "https://issuer.example.com"`,
+			private:  true,
+			expected: `sso: client_id is required when SSO is enabled`,
+		},
+		"missing issuer": {
+			sso:      `client_id = "terraform"`,
+			private:  true,
+			expected: `sso: issuer is required when SSO is enabled`,
+		},
+		"insecure issuer": {
+			sso:      "issuer = \"http://issuer.example.com\"\nclient_id = \"terraform\"",
+			private:  true,
+			expected: `expected "issuer" to have a url with schema of: "https"`,
+		},
+		"unknown mode": {
+			sso:      "mode = \"domain\"\nissuer = \"https://issuer.example.com\"\nclient_id = \"terraform\"",
+			private:  true,
+			expected: `expected mode to be one of \["default" "email" "group"\]`,
+		},
+		"allowed members in default mode": {
+			sso:      "issuer = \"https://issuer.example.com\"\nclient_id = \"terraform\"\nallowed_members = [\"bob@example.com\"]",
+			private:  true,
+			expected: `sso: allowed_members is only used with mode email or group`,
+		},
+		"group mode without allowed members": {
+			sso:      "mode = \"group\"\nissuer = \"https://issuer.example.com\"\nclient_id = \"terraform\"",
+			private:  true,
+			expected: `sso: mode group needs allowed_members, or nobody could join`,
+		},
+		"public network": {
+			sso:      "issuer = \"https://issuer.example.com\"\nclient_id = \"terraform\"",
+			private:  false,
+			expected: `sso: SSO can only be enabled on private networks`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				ProviderFactories: testAccProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_network" "test" {
+  name    = "acc-network-sso"
+  private = %t
+
+  sso {
+    %s
+  }
+}
+`, test.private, test.sso)),
+						PlanOnly:    true,
+						ExpectError: regexp.MustCompile(test.expected),
+					},
+				},
+			})
+		})
+	}
+
+	assert.Empty(t, srv.NetworkIDs())
+}
+
+func TestResourceNetworkStateUpgradeV0(t *testing.T) {
+	dns := func(domain string, servers ...interface{}) map[string]interface{} {
+		return map[string]interface{}{"domain": domain, "servers": servers}
+	}
+
+	tests := map[string]struct {
+		dns      interface{}
+		expected []interface{}
+	}{
+		"no dns": {
+			dns:      nil,
+			expected: []interface{}{},
+		},
+		"empty element": {
+			dns:      []interface{}{map[string]interface{}{"domain": "", "servers": nil}},
+			expected: []interface{}{},
+		},
+		"one block": {
+			dns:      []interface{}{dns("example.com", "10.0.0.2", "10.0.0.3")},
+			expected: []interface{}{dns("example.com", "10.0.0.2", "10.0.0.3")},
+		},
+		"several blocks": {
+			dns:      []interface{}{dns("a.example.com", "10.0.0.2"), dns("", ""), dns("b.example.com", "10.0.0.3")},
+			expected: []interface{}{dns("b.example.com", "10.0.0.3")},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			state, err := resourceNetworkStateUpgradeV0(context.Background(), map[string]interface{}{
+				"id":   "8056c2e21c000001",
+				"name": "alice",
+				"dns":  test.dns,
+			}, nil)
+
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{
+				"id":   "8056c2e21c000001",
+				"name": "alice",
+				"dns":  test.expected,
+			}, state)
+		})
+	}
+}
+
+func TestAccNetwork_dns(t *testing.T) {
+	srv := testAccCentral(t)
+
+	config := func(dns string) string {
+		return testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_network" "test" {
+  name = "acc-network-dns"
+  %s
+}
+`, dns))
+	}
+
+	checkDNS := func(domain string, servers []string) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			actual := srv.Network(s.RootModule().Resources["zerotier_network.test"].Primary.ID).Config.Dns
+
+			if ptrString(actual.Domain) != domain || !assert.ObjectsAreEqual(servers, *actual.Servers) {
+				return fmt.Errorf("expected DNS %s %v, got %s %v", domain, servers, ptrString(actual.Domain), *actual.Servers)
+			}
+
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckNetworkDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: config(""),
+				Check:  resource.TestCheckResourceAttr("zerotier_network.test", "dns.#", "0"),
+			},
+			{
+				Config: config(`
+  dns {
+    domain  = "acc.test"
+    servers = ["10.0.0.2", "fd00::2"]
+  }
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_network.test", "dns.#", "1"),
+					checkDNS("acc.test", []string{"10.0.0.2", "fd00::2"}),
+				),
+			},
+			{
+				// the order of the servers is their preference.
+				Config: config(`
+  dns {
+    domain  = "acc.test"
+    servers = ["fd00::2", "10.0.0.2"]
+  }
+`),
+				Check: checkDNS("acc.test", []string{"fd00::2", "10.0.0.2"}),
+			},
+			{
+				// domains that summed to the same characters used to collide.
+				Config: config(`
+  dns {
+    domain  = "cca.test"
+    servers = ["fd00::2", "10.0.0.2"]
+  }
+`),
+				Check: checkDNS("cca.test", []string{"fd00::2", "10.0.0.2"}),
+			},
+			{
+				Config: config(""),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_network.test", "dns.#", "0"),
+					checkDNS("", []string{}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccNetwork_invalidDNS(t *testing.T) {
+	srv := testAccCentral(t)
+
+	tests := map[string]struct {
+		dns      string
+		expected string
+	}{
+		"bad domain": {
+			dns:      "dns {\ndomain = \"acc..test\"\nservers = [\"10.0.0.2\"]\n}",
+			expected: `must be a lowercase domain name`,
+		},
+		"trailing dot": {
+			dns:      "dns {\ndomain = \"acc.test.\"\nservers = [\"10.0.0.2\"]\n}",
+			expected: `must be a lowercase domain name`,
+		},
+		"bad server": {
+			dns:      "dns {\ndomain = \"acc.test\"\nservers = [\"10.0.0.256\"]\n}",
+			expected: `expected servers to contain a valid IP, got: 10.0.0.256`,
+		},
+		"no servers": {
+			dns:      "dns {\ndomain = \"acc.test\"\nservers = []\n}",
+			expected: `requires 1 item minimum`,
+		},
+		"too many servers": {
+			dns:      "dns {\ndomain = \"acc.test\"\nservers = [\"10.0.0.1\", \"10.0.0.2\", \"10.0.0.3\", \"10.0.0.4\", \"10.0.0.5\"]\n}",
+			expected: `No more than 4 "servers" blocks are allowed|Too many list items`,
+		},
+		"two blocks": {
+			dns:      "dns {\ndomain = \"a.test\"\nservers = [\"10.0.0.2\"]\n}\ndns {\ndomain = \"b.test\"\nservers = [\"10.0.0.2\"]\n}",
+			expected: `No more than 1 "dns" blocks are allowed|Too many dns blocks`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				ProviderFactories: testAccProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: testAccConfig(srv, fmt.Sprintf(`
+resource "zerotier_network" "test" {
+  name = "acc-network-dns"
+
+  %s
+}
+`, test.dns)),
+						PlanOnly:    true,
+						ExpectError: regexp.MustCompile(test.expected),
+					},
+				},
+			})
+		})
+	}
+
+	assert.Empty(t, srv.NetworkIDs())
+}
@@ -0,0 +1,59 @@
+package zerotier
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
+)
+
+func testAccCheckTokenExists(srv *fakecentral.Server, name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		id := s.RootModule().Resources[name].Primary.ID
+
+		for _, token := range srv.APITokens() {
+			if token == id {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("token %q was not created", id)
+	}
+}
+
+func TestAccToken_basic(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy: func(s *terraform.State) error {
+			if tokens := srv.APITokens(); len(tokens) != 0 {
+				return fmt.Errorf("tokens were not deleted: %v", tokens)
+			}
+
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_token" "named" {
+  name = "hello-world"
+}
+
+resource "zerotier_token" "random" {}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_token.named", "id", "hello-world"),
+					resource.TestCheckResourceAttr("zerotier_token.named", "name", "hello-world"),
+					resource.TestCheckResourceAttrSet("zerotier_token.named", "token"),
+					resource.TestCheckResourceAttrSet("zerotier_token.random", "name"),
+					resource.TestCheckResourceAttrSet("zerotier_token.random", "token"),
+					testAccCheckTokenExists(srv, "zerotier_token.named"),
+					testAccCheckTokenExists(srv, "zerotier_token.random"),
+				),
+			},
+		},
+	})
+}
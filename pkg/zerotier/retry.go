@@ -0,0 +1,172 @@
+package zerotier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxRetries   = 5
+	defaultMaxRetryWait = "2m"
+
+	retryMinBackoff = 500 * time.Millisecond
+	retryMaxBackoff = 30 * time.Second
+)
+
+type notIdempotentKey struct{}
+
+// notIdempotent marks requests made with ctx as unsafe to repeat, such as
+// creating a network. These are only retried when the server has said it did
+// not process them.
+func notIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, notIdempotentKey{}, true)
+}
+
+// retryTransport retries requests that failed with a transient error: a
+// connection failure, 429 Too Many Requests or a 5xx from a gateway or an
+// overloaded server. Retries back off exponentially with jitter unless the
+// server asked for a specific delay with Retry-After, and stop after
+// maxRetries attempts or once the total time spent waiting would exceed
+// maxWait, whichever is first. The last response or error is then returned
+// as is.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	maxWait    time.Duration
+
+	// sleep is swapped out by tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newRetryTransport(next http.RoundTripper, maxRetries int, maxWait time.Duration) *retryTransport {
+	return &retryTransport{
+		next:       next,
+		maxRetries: maxRetries,
+		maxWait:    maxWait,
+		sleep:      sleepContext,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	var waited time.Duration
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := t.next.RoundTrip(req)
+
+		reason, ok := retryable(req, resp, err)
+		if !ok || attempt >= t.maxRetries {
+			return resp, err
+		}
+
+		wait, fromServer := retryAfter(resp)
+		if !fromServer {
+			wait = backoff(attempt)
+		}
+
+		if waited+wait > t.maxWait {
+			logrus.Debugf("%s %s: %s; not retrying, waiting another %v would exceed the limit of %v", req.Method, req.URL.Path, reason, wait, t.maxWait)
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		logrus.Debugf("%s %s: %s; retrying in %v (retry %d of %d)", req.Method, req.URL.Path, reason, wait, attempt+1, t.maxRetries)
+
+		if err := t.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+
+		waited += wait
+	}
+}
+
+// retryable reports whether the outcome of req is worth retrying, and why.
+func retryable(req *http.Request, resp *http.Response, err error) (string, bool) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return "", false
+		}
+
+		return err.Error(), req.Context().Value(notIdempotentKey{}) == nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return resp.Status, true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return resp.Status, req.Context().Value(notIdempotentKey{}) == nil
+	}
+
+	return "", false
+}
+
+// retryAfter returns the delay requested by the server's Retry-After header,
+// which may be in seconds or a HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(header); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// backoff returns the delay before the given retry: exponential, capped at
+// retryMaxBackoff, with the upper half randomized.
+func backoff(attempt int) time.Duration {
+	d := retryMaxBackoff
+	if attempt < 16 {
+		d = min(retryMinBackoff<<attempt, retryMaxBackoff)
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,167 @@
+package zerotier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
+)
+
+// testRetryBackend returns a central backend on top of a retry transport
+// that records its delays instead of sleeping.
+func testRetryBackend(t *testing.T, maxRetries int, maxWait time.Duration) (*fakecentral.Server, *centralBackend, *[]time.Duration) {
+	srv := testAccCentral(t)
+
+	waits := &[]time.Duration{}
+	transport := newRetryTransport(http.DefaultTransport, maxRetries, maxWait)
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+
+	c, err := newCentralBackend(srv.URL(), srv.Token, transport)
+	assert.NoError(t, err)
+
+	return srv, c, waits
+}
+
+func TestRetry_RetryAfter(t *testing.T) {
+	srv, c, waits := testRetryBackend(t, 5, time.Minute)
+
+	n, err := c.NewNetwork(context.Background(), "retry", &spec.Network{})
+	assert.NoError(t, err)
+
+	srv.FailNext(2, http.StatusTooManyRequests, "3")
+
+	_, err = c.GetNetwork(context.Background(), *n.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{3 * time.Second, 3 * time.Second}, *waits)
+}
+
+func TestRetry_Backoff(t *testing.T) {
+	srv, c, waits := testRetryBackend(t, 5, time.Minute)
+
+	srv.FailNext(4, http.StatusBadGateway, "")
+
+	_, err := c.GetNetworks(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, *waits, 4)
+
+	for i, wait := range *waits {
+		ceiling := retryMinBackoff << i
+		assert.GreaterOrEqual(t, wait, ceiling/2, "retry %d", i)
+		assert.LessOrEqual(t, wait, ceiling, "retry %d", i)
+	}
+}
+
+func TestRetry_MaxRetries(t *testing.T) {
+	srv, c, waits := testRetryBackend(t, 2, time.Minute)
+
+	srv.FailNext(10, http.StatusServiceUnavailable, "")
+
+	_, err := c.GetNetworks(context.Background())
+	assert.True(t, errors.Is(err, ztcentral.ErrStatus))
+	assert.ErrorContains(t, err, "503")
+	assert.Len(t, *waits, 2)
+	assert.Equal(t, 3, srv.Requests())
+}
+
+func TestRetry_MaxWait(t *testing.T) {
+	srv, c, waits := testRetryBackend(t, 10, 10*time.Second)
+
+	srv.FailNext(10, http.StatusTooManyRequests, "4")
+
+	_, err := c.GetNetworks(context.Background())
+	assert.ErrorContains(t, err, "429")
+	assert.Equal(t, []time.Duration{4 * time.Second, 4 * time.Second}, *waits)
+}
+
+func TestRetry_NotIdempotent(t *testing.T) {
+	srv, c, waits := testRetryBackend(t, 5, time.Minute)
+
+	// a 500 may have been processed; creating again could duplicate the
+	// network.
+	srv.FailNext(1, http.StatusInternalServerError, "")
+	_, err := c.NewNetwork(context.Background(), "retry", &spec.Network{})
+	assert.ErrorContains(t, err, "500")
+	assert.Empty(t, *waits)
+	assert.Empty(t, srv.NetworkIDs())
+
+	// but a 429 was not.
+	srv.FailNext(1, http.StatusTooManyRequests, "1")
+	_, err = c.NewNetwork(context.Background(), "retry", &spec.Network{})
+	assert.NoError(t, err)
+	assert.Len(t, *waits, 1)
+	assert.Len(t, srv.NetworkIDs(), 1)
+}
+
+func TestRetry_NotRetryable(t *testing.T) {
+	_, c, waits := testRetryBackend(t, 5, time.Minute)
+
+	_, err := c.GetNetwork(context.Background(), "0123456789abcdef")
+	assert.ErrorContains(t, err, "404")
+	assert.Empty(t, *waits)
+}
+
+func TestRetry_RequestBodyIsResent(t *testing.T) {
+	srv, c, _ := testRetryBackend(t, 5, time.Minute)
+
+	n, err := c.NewNetwork(context.Background(), "retry", &spec.Network{})
+	assert.NoError(t, err)
+
+	srv.FailNext(1, http.StatusBadGateway, "")
+
+	rules, err := c.UpdateNetworkRules(context.Background(), *n.Id, "drop;")
+	assert.NoError(t, err)
+	assert.Equal(t, "drop;", rules)
+}
+
+func TestRetry_ParseRetryAfter(t *testing.T) {
+	for header, expected := range map[string]time.Duration{
+		"":     0,
+		"0":    0,
+		"17":   17 * time.Second,
+		"-1":   0,
+		"soon": 0,
+		time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat): 0,
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if header != "" {
+			resp.Header.Set("Retry-After", header)
+		}
+
+		d, _ := retryAfter(resp)
+		assert.Equal(t, expected, d, "header %q", header)
+	}
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	d, ok := retryAfter(resp)
+	assert.True(t, ok)
+	assert.InDelta(t, time.Minute, d, float64(2*time.Second))
+}
+
+func TestRetry_ValidateSettings(t *testing.T) {
+	for value, valid := range map[string]bool{
+		"2m":    true,
+		"90s":   true,
+		"0s":    true,
+		"-1s":   false,
+		"5":     false,
+		"later": false,
+	} {
+		diags := validDuration(value, nil)
+		assert.Equal(t, valid, !diags.HasError(), value)
+
+		if !valid {
+			assert.True(t, strings.Contains(diags[0].Detail, value))
+		}
+	}
+}
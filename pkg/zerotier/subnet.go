@@ -0,0 +1,208 @@
+package zerotier
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+)
+
+// subnetResource is a subnet block, shorthand for a route and the
+// assignment pool inside it.
+func subnetResource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"cidr": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validCIDR,
+				Description:      "The subnet in CIDR notation, such as `10.0.0.0/24`. It is routed to the network.",
+			},
+			"reserved": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+				Description:      "How many addresses at the start of the subnet, after the network address, to leave out of the assignment pool, for gateways or static assignments.",
+			},
+		},
+	}
+}
+
+// mkSubnets derives the route and assignment pool of each subnet block.
+func mkSubnets(subnets interface{}) ([]spec.Route, []spec.IPRange, error) {
+	routes := []spec.Route{}
+	pools := []spec.IPRange{}
+
+	for _, s := range subnets.([]interface{}) {
+		if s == nil {
+			continue
+		}
+
+		m := s.(map[string]interface{})
+
+		route, pool, err := subnetRouteAndPool(m["cidr"].(string), m["reserved"].(int))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		routes = append(routes, route)
+		pools = append(pools, pool)
+	}
+
+	return routes, pools, nil
+}
+
+// subnetRouteAndPool returns the route for cidr and the pool of its usable
+// addresses past the reserved ones. The network address is never assigned,
+// and neither is the broadcast address of an IPv4 subnet.
+func subnetRouteAndPool(cidr string, reserved int) (spec.Route, spec.IPRange, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return spec.Route{}, spec.IPRange{}, fmt.Errorf("subnet %q is not a network in CIDR notation", cidr)
+	}
+	prefix = prefix.Masked()
+
+	if reserved < 0 {
+		return spec.Route{}, spec.IPRange{}, fmt.Errorf("subnet %s: reserved can't be negative", prefix)
+	}
+
+	start, ok := addrAdd(prefix.Addr(), uint64(reserved)+1)
+
+	end := lastAddr(prefix)
+	if prefix.Addr().Is4() {
+		end = end.Prev()
+	}
+
+	if !ok || !prefix.Contains(start) || start.Compare(end) > 0 {
+		return spec.Route{}, spec.IPRange{}, fmt.Errorf("subnet %s has no addresses left to assign after reserving %d", prefix, reserved)
+	}
+
+	return spec.Route{Target: stringPtr(prefix.String()), Via: stringPtr("")},
+		spec.IPRange{IpRangeStart: stringPtr(start.String()), IpRangeEnd: stringPtr(end.String())},
+		nil
+}
+
+// addrAdd adds n to addr, reporting whether it stayed within the address
+// family.
+func addrAdd(addr netip.Addr, n uint64) (netip.Addr, bool) {
+	b := addr.As16()
+
+	carry := n
+	for i := len(b) - 1; i >= 0 && carry > 0; i-- {
+		sum := uint64(b[i]) + carry&0xff
+		b[i] = byte(sum)
+		carry = carry>>8 + sum>>8
+	}
+
+	res := netip.AddrFrom16(b)
+	if addr.Is4() {
+		if !res.Is4In6() {
+			return netip.Addr{}, false
+		}
+
+		res = res.Unmap()
+	}
+
+	return res, carry == 0
+}
+
+// lastAddr returns the last address of prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+
+	for bit := prefix.Bits(); bit < len(b)*8; bit++ {
+		b[bit/8] |= 0x80 >> (bit % 8)
+	}
+
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// withSubnetRoutes adds the routes of the subnet blocks to routes, skipping
+// the ones already there.
+func withSubnetRoutes(routes, derived []spec.Route) []spec.Route {
+	res := append([]spec.Route{}, routes...)
+
+	for _, route := range derived {
+		if !containsRoute(res, route) {
+			res = append(res, route)
+		}
+	}
+
+	return res
+}
+
+// withSubnetPools adds the pools of the subnet blocks to pools, skipping the
+// ones already there.
+func withSubnetPools(pools, derived []spec.IPRange) []spec.IPRange {
+	res := append([]spec.IPRange{}, pools...)
+
+	for _, pool := range derived {
+		if !containsPool(res, pool) {
+			res = append(res, pool)
+		}
+	}
+
+	return res
+}
+
+// withoutSubnetRoutes removes the routes derived from subnet blocks from
+// what was read back, unless they are also configured as route blocks, so
+// that they don't show up as extra routes.
+func withoutSubnetRoutes(read *[]spec.Route, derived, configured []spec.Route) *[]spec.Route {
+	if read == nil {
+		return nil
+	}
+
+	res := []spec.Route{}
+	for _, route := range *read {
+		if containsRoute(derived, route) && !containsRoute(configured, route) {
+			continue
+		}
+
+		res = append(res, route)
+	}
+
+	return &res
+}
+
+// withoutSubnetPools is withoutSubnetRoutes for assignment pools.
+func withoutSubnetPools(read *[]spec.IPRange, derived, configured []spec.IPRange) *[]spec.IPRange {
+	if read == nil {
+		return nil
+	}
+
+	res := []spec.IPRange{}
+	for _, pool := range *read {
+		if containsPool(derived, pool) && !containsPool(configured, pool) {
+			continue
+		}
+
+		res = append(res, pool)
+	}
+
+	return &res
+}
+
+func containsRoute(routes []spec.Route, route spec.Route) bool {
+	for _, r := range routes {
+		if ptrString(r.Target) == ptrString(route.Target) && ptrString(r.Via) == ptrString(route.Via) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsPool(pools []spec.IPRange, pool spec.IPRange) bool {
+	for _, p := range pools {
+		if ptrString(p.IpRangeStart) == ptrString(pool.IpRangeStart) && ptrString(p.IpRangeEnd) == ptrString(pool.IpRangeEnd) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,280 @@
+package zerotier
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/zerotier/go-ztcentral/pkg/spec"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/fakecentral"
+)
+
+func TestSubnetRouteAndPool(t *testing.T) {
+	tests := map[string]struct {
+		cidr     string
+		reserved int
+		target   string
+		start    string
+		end      string
+		err      string
+	}{
+		"ipv4": {
+			cidr:   "10.0.0.0/24",
+			target: "10.0.0.0/24",
+			start:  "10.0.0.1",
+			end:    "10.0.0.254",
+		},
+		"ipv4 reserved": {
+			cidr:     "10.0.0.0/24",
+			reserved: 9,
+			target:   "10.0.0.0/24",
+			start:    "10.0.0.10",
+			end:      "10.0.0.254",
+		},
+		"ipv4 reserved across octets": {
+			cidr:     "10.0.0.0/16",
+			reserved: 299,
+			target:   "10.0.0.0/16",
+			start:    "10.0.1.44",
+			end:      "10.0.255.254",
+		},
+		"host bits": {
+			cidr:   "192.168.1.77/30",
+			target: "192.168.1.76/30",
+			start:  "192.168.1.77",
+			end:    "192.168.1.78",
+		},
+		"ipv6": {
+			cidr:     "fd00::/64",
+			reserved: 15,
+			target:   "fd00::/64",
+			start:    "fd00::10",
+			end:      "fd00::ffff:ffff:ffff:ffff",
+		},
+		"ipv4 too small": {
+			cidr: "10.0.0.0/31",
+			err:  "subnet 10.0.0.0/31 has no addresses left to assign after reserving 0",
+		},
+		"everything reserved": {
+			cidr:     "10.0.0.0/24",
+			reserved: 254,
+			err:      "subnet 10.0.0.0/24 has no addresses left to assign after reserving 254",
+		},
+		"not cidr": {
+			cidr: "10.0.0.0",
+			err:  `subnet "10.0.0.0" is not a network in CIDR notation`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			route, pool, err := subnetRouteAndPool(test.cidr, test.reserved)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.target, ptrString(route.Target))
+			assert.Equal(t, "", ptrString(route.Via))
+			assert.Equal(t, test.start, ptrString(pool.IpRangeStart))
+			assert.Equal(t, test.end, ptrString(pool.IpRangeEnd))
+		})
+	}
+}
+
+func TestSubnetFilters(t *testing.T) {
+	route := func(target string) spec.Route {
+		return spec.Route{Target: stringPtr(target), Via: stringPtr("")}
+	}
+
+	derived := []spec.Route{route("10.0.0.0/24"), route("10.1.0.0/24")}
+	configured := []spec.Route{route("10.1.0.0/24"), route("10.2.0.0/24")}
+
+	all := withSubnetRoutes(configured, derived)
+	assert.Equal(t, []spec.Route{route("10.1.0.0/24"), route("10.2.0.0/24"), route("10.0.0.0/24")}, all)
+
+	read := append(all, route("10.3.0.0/24"))
+	assert.Equal(t, &[]spec.Route{route("10.1.0.0/24"), route("10.2.0.0/24"), route("10.3.0.0/24")}, withoutSubnetRoutes(&read, derived, configured))
+	assert.Nil(t, withoutSubnetRoutes(nil, derived, configured))
+
+	pool := spec.IPRange{IpRangeStart: stringPtr("10.0.0.1"), IpRangeEnd: stringPtr("10.0.0.254")}
+	other := spec.IPRange{IpRangeStart: stringPtr("10.2.0.1"), IpRangeEnd: stringPtr("10.2.0.9")}
+
+	pools := withSubnetPools([]spec.IPRange{other}, []spec.IPRange{pool})
+	assert.Equal(t, []spec.IPRange{other, pool}, pools)
+	assert.Equal(t, &[]spec.IPRange{other}, withoutSubnetPools(&pools, []spec.IPRange{pool}, []spec.IPRange{other}))
+	assert.Equal(t, &[]spec.IPRange{other, pool}, withoutSubnetPools(&pools, []spec.IPRange{pool}, []spec.IPRange{other, pool}))
+}
+
+// testAccCheckNetworkAddresses checks the route targets and pools (as
+// start-end) Central has for the network.
+func testAccCheckNetworkAddresses(srv *fakecentral.Server, routes []string, pools []string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		n := srv.Network(s.RootModule().Resources["zerotier_network.test"].Primary.ID)
+
+		actualRoutes := []string{}
+		for _, route := range *n.Config.Routes {
+			actualRoutes = append(actualRoutes, ptrString(route.Target))
+		}
+
+		actualPools := []string{}
+		for _, pool := range *n.Config.IpAssignmentPools {
+			actualPools = append(actualPools, ptrString(pool.IpRangeStart)+"-"+ptrString(pool.IpRangeEnd))
+		}
+
+		if !assert.ObjectsAreEqual(routes, actualRoutes) || !assert.ObjectsAreEqual(pools, actualPools) {
+			return fmt.Errorf("expected routes %v and pools %v, got %v and %v", routes, pools, actualRoutes, actualPools)
+		}
+
+		return nil
+	}
+}
+
+func TestAccNetwork_subnet(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccCheckNetworkDestroyed(srv),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-subnet"
+
+  subnet {
+    cidr     = "10.0.0.0/24"
+    reserved = 9
+  }
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_network.test", "route.#", "0"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "assignment_pool.#", "0"),
+					testAccCheckNetworkAddresses(srv, []string{"10.0.0.0/24"}, []string{"10.0.0.10-10.0.0.254"}),
+				),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-subnet"
+
+  subnet {
+    cidr = "10.0.0.0/24"
+  }
+
+  subnet {
+    cidr     = "fd00::/64"
+    reserved = 255
+  }
+
+  route {
+    target = "10.0.0.0/24"
+  }
+
+  route {
+    target = "192.168.0.0/16"
+    via    = "10.0.0.1"
+  }
+
+  assignment_pool {
+    start = "10.0.0.100"
+    end   = "10.0.0.200"
+  }
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_network.test", "route.#", "2"),
+					resource.TestCheckResourceAttr("zerotier_network.test", "assignment_pool.#", "1"),
+					resource.TestCheckTypeSetElemNestedAttrs("zerotier_network.test", "route.*", map[string]string{"target": "10.0.0.0/24"}),
+					resource.TestCheckTypeSetElemNestedAttrs("zerotier_network.test", "assignment_pool.*", map[string]string{"start": "10.0.0.100"}),
+					testAccCheckNetworkAddresses(srv,
+						[]string{"192.168.0.0/16", "10.0.0.0/24", "fd00::/64"},
+						[]string{"10.0.0.100-10.0.0.200", "10.0.0.1-10.0.0.254", "fd00::100-fd00::ffff:ffff:ffff:ffff"},
+					),
+				),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-subnet"
+
+  route {
+    target = "10.0.0.0/24"
+  }
+
+  assignment_pool {
+    start = "10.0.0.100"
+    end   = "10.0.0.200"
+  }
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("zerotier_network.test", "route.#", "1"),
+					testAccCheckNetworkAddresses(srv, []string{"10.0.0.0/24"}, []string{"10.0.0.100-10.0.0.200"}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccNetwork_invalidSubnet(t *testing.T) {
+	srv := testAccCentral(t)
+
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-subnet"
+
+  subnet {
+    cidr = "10.0.0.0"
+  }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`expected "cidr" to be a valid CIDR Value`),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-subnet"
+
+  subnet {
+    cidr     = "10.0.0.0/29"
+    reserved = 6
+  }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`subnet 10.0.0.0/29 has no addresses left to assign after reserving 6`),
+			},
+			{
+				Config: testAccConfig(srv, `
+resource "zerotier_network" "test" {
+  name = "acc-network-subnet"
+
+  subnet {
+    cidr = "10.0.0.0/24"
+  }
+
+  assignment_pool {
+    start = "10.0.1.1"
+    end   = "10.0.1.9"
+  }
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`assignment_pool 10.0.1.1-10.0.1.9: not inside any route target\s+\(10.0.0.0/24\)`),
+			},
+		},
+	})
+
+	assert.Empty(t, srv.NetworkIDs())
+}
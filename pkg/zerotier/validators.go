@@ -2,9 +2,30 @@ package zerotier
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/zerotier/terraform-provider-zerotier/pkg/rules"
+)
+
+var (
+	networkIDRegexp = regexp.MustCompile(`^[0-9a-f]{16}$`)
+	memberIDRegexp  = regexp.MustCompile(`^[0-9a-f]{10}$`)
+	domainRegexp    = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+	validNetworkID = validation.ToDiagFunc(validation.StringMatch(networkIDRegexp, "must be 16 lowercase hexadecimal characters"))
+	validMemberID  = validation.ToDiagFunc(validation.StringMatch(memberIDRegexp, "must be 10 lowercase hexadecimal characters"))
+	validCIDR      = validation.ToDiagFunc(validation.IsCIDR)
+	validIP        = validation.ToDiagFunc(validation.IsIPAddress)
+	validDomain    = validation.ToDiagFunc(validation.All(
+		validation.StringLenBetween(1, 253),
+		validation.StringMatch(domainRegexp, "must be a lowercase domain name without a trailing dot, such as example.com"),
+	))
 )
 
 func strNonEmpty(i interface{}) diag.Diagnostics {
@@ -23,3 +44,48 @@ func strNonEmpty(i interface{}) diag.Diagnostics {
 
 	return nil
 }
+
+func validDuration(i interface{}, path cty.Path) diag.Diagnostics {
+	s, ok := i.(string)
+	if !ok {
+		return diag.FromErr(errors.New("not a string"))
+	}
+
+	if d, err := time.ParseDuration(s); err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid duration",
+			Detail:        fmt.Sprintf("%q is not a valid duration, such as 90s or 5m: %v", s, err),
+			AttributePath: path,
+		}}
+	} else if d < 0 {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid duration",
+			Detail:        fmt.Sprintf("%q must not be negative", s),
+			AttributePath: path,
+		}}
+	}
+
+	return nil
+}
+
+// validFlowRules compiles the rules so mistakes are reported at plan time,
+// before the network is created.
+func validFlowRules(i interface{}, path cty.Path) diag.Diagnostics {
+	s, ok := i.(string)
+	if !ok {
+		return diag.FromErr(errors.New("not a string"))
+	}
+
+	if _, err := rules.Compile(s); err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid flow rules",
+			Detail:        err.Error(),
+			AttributePath: path,
+		}}
+	}
+
+	return nil
+}